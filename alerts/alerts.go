@@ -0,0 +1,275 @@
+// Package alerts evaluates alert rules against cached Copilot usage and
+// token health and notifies webhooks when a rule fires or resolves.
+package alerts
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"internal/config"
+	"internal/logger"
+	"internal/services/github"
+	"internal/state"
+	"internal/usage"
+)
+
+const defaultQuota = "premium_interactions"
+
+// Status is whether an alert started or stopped.
+type Status string
+
+const (
+	Firing   Status = "firing"
+	Resolved Status = "resolved"
+)
+
+// Alert is one notification about a rule.
+type Alert struct {
+	Rule      string    `json:"rule"`
+	Status    Status    `json:"status"`
+	Metric    string    `json:"metric"`
+	Quota     string    `json:"quota,omitempty"`
+	Value     float64   `json:"value"`
+	Threshold float64   `json:"threshold"`
+	Message   string    `json:"message"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// ruleState tracks one rule so an incident is only notified once and
+// repeated incidents respect the cooldown.
+type ruleState struct {
+	firing   bool
+	notified bool
+	lastSent time.Time
+}
+
+// Manager evaluates alert rules and sends notifications.
+type Manager struct {
+	cfg    config.AlertsConfig
+	client *http.Client
+
+	mu     sync.Mutex
+	states map[string]*ruleState
+}
+
+// New validates cfg and returns a manager for it.
+func New(cfg config.AlertsConfig, client *http.Client) (*Manager, error) {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	names := make(map[string]bool, len(cfg.Webhooks))
+	for i, hook := range cfg.Webhooks {
+		if hook.URL == "" {
+			return nil, fmt.Errorf("alerts: webhook %d has no url", i)
+		}
+		switch hook.Format {
+		case "", config.WebhookGeneric, config.WebhookSlack:
+		default:
+			return nil, fmt.Errorf("alerts: webhook %d has unknown format %q", i, hook.Format)
+		}
+		names[hook.Name] = true
+	}
+	seen := make(map[string]bool, len(cfg.Rules))
+	for i, rule := range cfg.Rules {
+		if rule.Name == "" {
+			return nil, fmt.Errorf("alerts: rule %d has no name", i)
+		}
+		if seen[rule.Name] {
+			return nil, fmt.Errorf("alerts: duplicate rule %q", rule.Name)
+		}
+		seen[rule.Name] = true
+		switch rule.Metric {
+		case config.AlertQuotaRemaining, config.AlertQuotaRemainingPercent, config.AlertTokenRefreshFailures:
+		default:
+			return nil, fmt.Errorf("alerts: rule %q has unknown metric %q", rule.Name, rule.Metric)
+		}
+		if (rule.Below == nil) == (rule.AtLeast == nil) {
+			return nil, fmt.Errorf("alerts: rule %q needs exactly one of below or at_least", rule.Name)
+		}
+		for _, name := range rule.Webhooks {
+			if !names[name] {
+				return nil, fmt.Errorf("alerts: rule %q references unknown webhook %q", rule.Name, name)
+			}
+		}
+	}
+	return &Manager{cfg: cfg, client: client, states: make(map[string]*ruleState)}, nil
+}
+
+// Start evaluates the rules every CheckSeconds until the returned function
+// is called.
+func (m *Manager) Start(ctx context.Context, s *state.State) context.CancelFunc {
+	ctx, cancel := context.WithCancel(ctx)
+	go func() {
+		ticker := time.NewTicker(time.Duration(m.cfg.CheckSeconds) * time.Second)
+		defer ticker.Stop()
+		for {
+			m.Check(ctx, s)
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+	return cancel
+}
+
+// Check evaluates every rule once against the current state.
+func (m *Manager) Check(ctx context.Context, s *state.State) {
+	cached := usage.Cached(s)
+	failures := 0
+	s.Read(func(st *state.State) { failures = st.CopilotTokenFailures })
+
+	now := time.Now()
+	for _, rule := range m.cfg.Rules {
+		value, ok := metricValue(rule, cached, failures)
+		if !ok {
+			continue
+		}
+		if alert, send := m.evaluate(rule, value, now); send {
+			m.notify(ctx, rule, alert)
+		}
+	}
+}
+
+// metricValue reads the rule's metric; ok is false when it is unknown,
+// e.g. before usage was fetched or for unlimited quotas.
+func metricValue(rule config.AlertRule, cached *github.CopilotUsageResponse, failures int) (float64, bool) {
+	if rule.Metric == config.AlertTokenRefreshFailures {
+		return float64(failures), true
+	}
+	if cached == nil {
+		return 0, false
+	}
+	name := rule.Quota
+	if name == "" {
+		name = defaultQuota
+	}
+	for _, quota := range cached.QuotaSnapshots.All() {
+		if quota.Name != name {
+			continue
+		}
+		if quota.Unlimited {
+			return 0, false
+		}
+		if rule.Metric == config.AlertQuotaRemainingPercent {
+			return quota.PercentRemaining, true
+		}
+		return quota.Remaining, true
+	}
+	return 0, false
+}
+
+// evaluate updates the rule's state and returns the alert to send, if any.
+// An incident is notified once when it starts, or once the cooldown since
+// the previous notification has passed, and resolved when it ends.
+func (m *Manager) evaluate(rule config.AlertRule, value float64, now time.Time) (Alert, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	st, ok := m.states[rule.Name]
+	if !ok {
+		st = &ruleState{}
+		m.states[rule.Name] = st
+	}
+
+	threshold, breached := 0.0, false
+	if rule.Below != nil {
+		threshold, breached = *rule.Below, value < *rule.Below
+	} else {
+		threshold, breached = *rule.AtLeast, value >= *rule.AtLeast
+	}
+
+	alert := Alert{
+		Rule:      rule.Name,
+		Metric:    rule.Metric,
+		Value:     value,
+		Threshold: threshold,
+		Timestamp: now.UTC(),
+	}
+	if rule.Metric != config.AlertTokenRefreshFailures {
+		alert.Quota = rule.Quota
+		if alert.Quota == "" {
+			alert.Quota = defaultQuota
+		}
+	}
+
+	if breached {
+		if !st.firing {
+			st.firing = true
+			st.notified = false
+		}
+		if st.notified || now.Sub(st.lastSent) < m.cooldown(rule) {
+			return Alert{}, false
+		}
+		st.notified = true
+		st.lastSent = now
+		alert.Status = Firing
+	} else {
+		if !st.firing {
+			return Alert{}, false
+		}
+		st.firing = false
+		if !st.notified {
+			return Alert{}, false
+		}
+		st.notified = false
+		alert.Status = Resolved
+	}
+	alert.Message = describe(alert)
+	return alert, true
+}
+
+func (m *Manager) cooldown(rule config.AlertRule) time.Duration {
+	seconds := *m.cfg.CooldownSeconds
+	if rule.CooldownSeconds != nil {
+		seconds = *rule.CooldownSeconds
+	}
+	return time.Duration(seconds) * time.Second
+}
+
+func describe(alert Alert) string {
+	var what string
+	switch alert.Metric {
+	case config.AlertQuotaRemainingPercent:
+		what = fmt.Sprintf("%s quota at %.1f%% remaining (threshold %g%%)", alert.Quota, alert.Value, alert.Threshold)
+	case config.AlertQuotaRemaining:
+		what = fmt.Sprintf("%s quota at %g remaining (threshold %g)", alert.Quota, alert.Value, alert.Threshold)
+	case config.AlertTokenRefreshFailures:
+		what = fmt.Sprintf("Copilot token refresh failed %g times in a row (threshold %g)", alert.Value, alert.Threshold)
+		if alert.Status == Resolved {
+			what = "Copilot token refreshed again"
+		}
+	}
+	if alert.Status == Resolved {
+		return fmt.Sprintf("Resolved %s: %s", alert.Rule, what)
+	}
+	return fmt.Sprintf("Alert %s: %s", alert.Rule, what)
+}
+
+func (m *Manager) notify(ctx context.Context, rule config.AlertRule, alert Alert) {
+	logger.Warn("%s", alert.Message)
+	for _, hook := range m.cfg.Webhooks {
+		if !targets(rule, hook) {
+			continue
+		}
+		if err := send(ctx, m.client, hook, alert); err != nil {
+			logger.Error("Failed to send alert %s to webhook %s: %v", alert.Rule, hookName(hook), err)
+		}
+	}
+}
+
+func targets(rule config.AlertRule, hook config.Webhook) bool {
+	if len(rule.Webhooks) == 0 {
+		return true
+	}
+	for _, name := range rule.Webhooks {
+		if name == hook.Name {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,63 @@
+package alerts
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+
+	"internal/config"
+)
+
+// payload renders alert in the webhook's format: Slack incoming webhooks
+// take a text message, generic webhooks the alert itself.
+func payload(hook config.Webhook, alert Alert) ([]byte, error) {
+	if hook.Format == config.WebhookSlack {
+		icon := ":warning:"
+		if alert.Status == Resolved {
+			icon = ":white_check_mark:"
+		}
+		return json.Marshal(map[string]string{"text": icon + " " + alert.Message})
+	}
+	return json.Marshal(alert)
+}
+
+func send(ctx context.Context, client *http.Client, hook config.Webhook, alert Alert) error {
+	body, err := payload(hook, alert)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, hook.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for key, value := range hook.Headers {
+		req.Header.Set(key, value)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// hookName identifies a webhook in logs without leaking secrets in its URL.
+func hookName(hook config.Webhook) string {
+	if hook.Name != "" {
+		return hook.Name
+	}
+	if u, err := url.Parse(hook.URL); err == nil {
+		return u.Host
+	}
+	return "webhook"
+}
@@ -6,6 +6,7 @@ import (
 
 	"internal/logger"
 	"internal/paths"
+	"internal/services/vscode"
 	"internal/state"
 	"internal/token"
 )
@@ -33,3 +34,37 @@ func RunAuth(ctx context.Context, opts RunAuthOptions) error {
 
 	return token.SetupGitHubToken(ctx, state.Shared, paths.Default, token.SetupGitHubTokenOptions{Force: true}, http.DefaultClient)
 }
+
+type bootstrapAuthOptions struct {
+	Client      *http.Client
+	GitHubToken string
+	// Copilot also detects the VSCode version and fetches a Copilot token.
+	Copilot bool
+}
+
+// bootstrapAuth sets up the GitHub token, from opts or the device flow, and
+// the Copilot token when asked. The returned function stops refreshing the
+// Copilot token; it is a no-op otherwise.
+func bootstrapAuth(ctx context.Context, opts bootstrapAuthOptions) (context.CancelFunc, error) {
+	if opts.Copilot {
+		version := vscode.GetVersion(ctx, opts.Client)
+		state.Shared.Update(func(st *state.State) {
+			st.VSCodeVersion = version
+		})
+		logger.Info("Using VSCode version: %s", version)
+	}
+
+	if opts.GitHubToken != "" {
+		state.Shared.Update(func(st *state.State) {
+			st.GitHubToken = opts.GitHubToken
+		})
+		logger.Info("Using provided GitHub token")
+	} else if err := token.SetupGitHubToken(ctx, state.Shared, paths.Default, token.SetupGitHubTokenOptions{}, opts.Client); err != nil {
+		return nil, err
+	}
+
+	if !opts.Copilot {
+		return func() {}, nil
+	}
+	return token.SetupCopilotToken(ctx, state.Shared, opts.Client)
+}
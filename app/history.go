@@ -0,0 +1,40 @@
+package app
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"internal/paths"
+	"internal/usage"
+)
+
+type RunUsageHistoryOptions struct {
+	Days  int
+	Quota string
+	JSON  bool
+}
+
+// RunUsageHistory prints burn rate and projections from the quota snapshots
+// recorded by a running server.
+func RunUsageHistory(opts RunUsageHistoryOptions) error {
+	if opts.Days <= 0 || opts.Days > usage.MaxTrendDays {
+		return fmt.Errorf("invalid value for --days: %d (must be between 1 and %d)", opts.Days, usage.MaxTrendDays)
+	}
+
+	now := time.Now()
+	snapshots, err := usage.NewHistory(paths.Default.UsageHistory).Read(now.AddDate(0, 0, -max(opts.Days, 7)-1))
+	if err != nil {
+		return err
+	}
+	trends := usage.FilterTrends(usage.Trends(snapshots, opts.Days, now, time.Local), opts.Quota)
+
+	if opts.JSON {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(trends)
+	}
+	usage.WriteTrends(os.Stdout, trends)
+	return nil
+}
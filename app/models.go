@@ -0,0 +1,160 @@
+package app
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"internal/logger"
+	"internal/paths"
+	"internal/services/copilot"
+	"internal/state"
+)
+
+type RunModelsOptions struct {
+	JSON        bool
+	GitHubToken string
+	AccountType string
+	// Supports lists capabilities every model shown must have.
+	Supports []string
+	// Endpoint keeps models that list this supported endpoint.
+	Endpoint string
+}
+
+// RunModels fetches the Copilot model list and prints its capabilities.
+func RunModels(ctx context.Context, opts RunModelsOptions) error {
+	for _, capability := range opts.Supports {
+		if _, ok := modelCapabilities[capability]; !ok {
+			return fmt.Errorf("invalid value for --supports: %q (use %s)", capability, strings.Join(capabilityNames(), ", "))
+		}
+	}
+	if opts.JSON {
+		logger.SetOutput(os.Stderr)
+	}
+
+	state.Shared.Update(func(st *state.State) {
+		if opts.AccountType != "" {
+			st.AccountType = opts.AccountType
+		}
+	})
+
+	if err := paths.EnsurePaths(paths.Default); err != nil {
+		return err
+	}
+
+	client := http.DefaultClient
+	cancelRefresh, err := bootstrapAuth(ctx, bootstrapAuthOptions{Client: client, GitHubToken: opts.GitHubToken, Copilot: true})
+	if err != nil {
+		return err
+	}
+	cancelRefresh()
+
+	models, err := copilot.GetModels(ctx, state.Shared, client)
+	if err != nil {
+		return err
+	}
+
+	matched := []copilot.Model{}
+	for _, model := range models.Data {
+		if matchesModel(model, opts) {
+			matched = append(matched, model)
+		}
+	}
+
+	if opts.JSON {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(matched)
+	}
+	return writeModelsTable(matched)
+}
+
+// modelCapabilities maps --supports values to the capability flags they
+// check.
+var modelCapabilities = map[string]func(copilot.ModelSupports) *bool{
+	"tools":              func(s copilot.ModelSupports) *bool { return s.ToolCalls },
+	"parallel_tools":     func(s copilot.ModelSupports) *bool { return s.ParallelToolCalls },
+	"vision":             func(s copilot.ModelSupports) *bool { return s.Vision },
+	"streaming":          func(s copilot.ModelSupports) *bool { return s.Streaming },
+	"structured_outputs": func(s copilot.ModelSupports) *bool { return s.StructuredOutputs },
+	"dimensions":         func(s copilot.ModelSupports) *bool { return s.Dimensions },
+}
+
+func capabilityNames() []string {
+	return []string{"tools", "parallel_tools", "vision", "streaming", "structured_outputs", "dimensions"}
+}
+
+func matchesModel(model copilot.Model, opts RunModelsOptions) bool {
+	if opts.Endpoint != "" && !model.SupportsEndpoint(opts.Endpoint) {
+		return false
+	}
+	for _, capability := range opts.Supports {
+		if supported := modelCapabilities[capability](model.Capabilities.Supports); supported == nil || !*supported {
+			return false
+		}
+	}
+	return true
+}
+
+func writeModelsTable(models []copilot.Model) error {
+	if len(models) == 0 {
+		fmt.Println("No models matched.")
+		return nil
+	}
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "ID\tVENDOR\tFAMILY\tCONTEXT\tPROMPT\tOUTPUT\tTOOLS\tVISION\tSTREAM\tSTRUCTURED\tENDPOINTS\tPREVIEW\tPOLICY")
+	for _, model := range models {
+		limits := model.Capabilities.Limits
+		supports := model.Capabilities.Supports
+		endpoints := "-"
+		if len(model.SupportedEndpoints) > 0 {
+			endpoints = strings.Join(model.SupportedEndpoints, ",")
+		}
+		policy := "-"
+		if model.Policy != nil && model.Policy.State != "" {
+			policy = model.Policy.State
+		}
+		fmt.Fprintf(
+			tw,
+			"%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
+			model.ID,
+			cmp.Or(model.Vendor, "-"),
+			cmp.Or(model.Capabilities.Family, "-"),
+			formatLimit(limits.MaxContextWindowTokens),
+			formatLimit(limits.MaxPromptTokens),
+			formatLimit(limits.MaxOutputTokens),
+			formatSupport(supports.ToolCalls),
+			formatSupport(supports.Vision),
+			formatSupport(supports.Streaming),
+			formatSupport(supports.StructuredOutputs),
+			endpoints,
+			formatSupport(&model.Preview),
+			policy,
+		)
+	}
+	return tw.Flush()
+}
+
+func formatLimit(value *int) string {
+	if value == nil {
+		return "-"
+	}
+	return strconv.Itoa(*value)
+}
+
+func formatSupport(value *bool) string {
+	switch {
+	case value == nil:
+		return "-"
+	case *value:
+		return "yes"
+	default:
+		return "no"
+	}
+}
@@ -0,0 +1,58 @@
+package app
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"internal/paths"
+	"internal/usage"
+)
+
+type RunReportOptions struct {
+	Since   string
+	Until   string
+	GroupBy string
+	Format  string
+}
+
+// RunReport aggregates the local usage ledger and prints it.
+func RunReport(opts RunReportOptions) error {
+	groupBy, err := usage.ParseGroupBy(opts.GroupBy)
+	if err != nil {
+		return err
+	}
+	since, err := usage.ParseDay(opts.Since, time.Local)
+	if err != nil {
+		return fmt.Errorf("invalid value for --since: %w", err)
+	}
+	until, err := usage.ParseDay(opts.Until, time.Local)
+	if err != nil {
+		return fmt.Errorf("invalid value for --until: %w", err)
+	}
+	if !until.IsZero() {
+		until = until.AddDate(0, 0, 1)
+	}
+
+	entries, err := usage.NewLedger(paths.Default.UsageLedger).Read(since, until)
+	if err != nil {
+		return err
+	}
+	rows := usage.Aggregate(entries, groupBy, time.Local)
+
+	switch opts.Format {
+	case "json":
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(rows)
+	case "", "table":
+		if len(rows) == 0 {
+			fmt.Println("No requests recorded.")
+			return nil
+		}
+		return usage.WriteTable(os.Stdout, rows, groupBy)
+	default:
+		return fmt.Errorf("invalid value for --format: %q (use table or json)", opts.Format)
+	}
+}
@@ -6,13 +6,15 @@ import (
 	"net/http"
 	"time"
 
+	"internal/alerts"
+	"internal/config"
 	"internal/logger"
 	"internal/paths"
 	"internal/server"
 	"internal/services/copilot"
-	"internal/services/vscode"
 	"internal/state"
-	"internal/token"
+	"internal/upstream"
+	"internal/usage"
 )
 
 type RunServerOptions struct {
@@ -25,6 +27,7 @@ type RunServerOptions struct {
 	GitHubToken      string
 	ShowToken        bool
 	ProxyEnv         bool
+	BatchWorkers     int
 }
 
 func RunServer(ctx context.Context, opts RunServerOptions) error {
@@ -56,26 +59,20 @@ func RunServer(ctx context.Context, opts RunServerOptions) error {
 		return err
 	}
 
-	client := &http.Client{}
-
-	version := vscode.GetVersion(ctx, client)
+	cfg, err := config.Load(paths.Default.ConfigPath)
+	if err != nil {
+		return err
+	}
 	state.Shared.Update(func(st *state.State) {
-		st.VSCodeVersion = version
+		st.Config = cfg
 	})
-	logger.Info("Using VSCode version: %s", version)
-
-	if opts.GitHubToken != "" {
-		state.Shared.Update(func(st *state.State) {
-			st.GitHubToken = opts.GitHubToken
-		})
-		logger.Info("Using provided GitHub token")
-	} else {
-		if err := token.SetupGitHubToken(ctx, state.Shared, paths.Default, token.SetupGitHubTokenOptions{}, client); err != nil {
-			return err
-		}
+	if len(cfg.APIKeys) > 0 {
+		logger.Info("Loaded %d API keys from %s", len(cfg.APIKeys), paths.Default.ConfigPath)
 	}
 
-	cancelRefresh, err := token.SetupCopilotToken(ctx, state.Shared, client)
+	client := &http.Client{Transport: upstream.NewTransport(http.DefaultTransport, cfg.Upstream)}
+
+	cancelRefresh, err := bootstrapAuth(ctx, bootstrapAuthOptions{Client: client, GitHubToken: opts.GitHubToken, Copilot: true})
 	if err != nil {
 		return err
 	}
@@ -94,12 +91,55 @@ func RunServer(ctx context.Context, opts RunServerOptions) error {
 		logger.Info("- %s", model.ID)
 	}
 
+	if poll := *cfg.Quota.PollSeconds; poll > 0 {
+		stopPolling := usage.StartPolling(ctx, state.Shared, client, time.Duration(poll)*time.Second)
+		defer stopPolling()
+	}
+
+	history := usage.NewHistory(paths.Default.UsageHistory)
+	if interval := *cfg.Quota.HistorySeconds; interval > 0 {
+		stopSnapshots := usage.StartSnapshots(ctx, state.Shared, client, history, time.Duration(interval)*time.Second)
+		defer stopSnapshots()
+	}
+
+	if len(cfg.Alerts.Rules) > 0 {
+		// Quota rules only see usage cached by polling.
+		if *cfg.Quota.PollSeconds <= 0 {
+			for _, rule := range cfg.Alerts.Rules {
+				if rule.Metric != config.AlertTokenRefreshFailures {
+					return fmt.Errorf("alerts: rule %q needs quota.poll_seconds to be greater than 0", rule.Name)
+				}
+			}
+		}
+		alerter, err := alerts.New(cfg.Alerts, &http.Client{Timeout: 10 * time.Second})
+		if err != nil {
+			return err
+		}
+		stopAlerts := alerter.Start(ctx, state.Shared)
+		defer stopAlerts()
+		logger.Info("Loaded %d alert rules", len(cfg.Alerts.Rules))
+	}
+
 	now := time.Now().UnixMilli()
 	state.Shared.Update(func(st *state.State) {
 		st.ServerStartUnixMs = &now
 	})
 
 	srv := server.New(state.Shared, client)
+	if err := srv.EnableBatches(paths.Default, opts.BatchWorkers); err != nil {
+		return err
+	}
+	if err := srv.EnableResponseStore(paths.Default.ResponsesDir); err != nil {
+		return err
+	}
+	if err := srv.EnableConversations(paths.Default.ConversationsDir); err != nil {
+		return err
+	}
+	if err := srv.EnableBudgets(paths.Default.BudgetLedger); err != nil {
+		return err
+	}
+	srv.EnableUsageLedger(paths.Default.UsageLedger)
+	srv.EnableUsageHistory(history)
 	httpSrv := &http.Server{
 		Addr:    fmt.Sprintf(":%d", opts.Port),
 		Handler: srv.Handler(),
@@ -2,64 +2,196 @@ package app
 
 import (
 	"context"
+	"encoding/csv"
+	"encoding/json"
 	"fmt"
+	"io"
 	"math"
 	"net/http"
+	"os"
+	"strconv"
+	"text/tabwriter"
+	"time"
 
 	"internal/logger"
 	"internal/paths"
 	"internal/services/github"
 	"internal/state"
-	"internal/token"
 )
 
-func RunCheckUsage(ctx context.Context) error {
+type RunCheckUsageOptions struct {
+	// Format is one of text, table, csv or json.
+	Format      string
+	Watch       time.Duration
+	GitHubToken string
+	AccountType string
+}
+
+func RunCheckUsage(ctx context.Context, opts RunCheckUsageOptions) error {
+	format := opts.Format
+	if format == "" {
+		format = "text"
+	}
+	switch format {
+	case "text", "table":
+	case "csv", "json":
+		// Keep stdout parseable; device flow prompts still reach the user.
+		logger.SetOutput(os.Stderr)
+	default:
+		return fmt.Errorf("invalid value for --format: %q (use text, table, csv or json)", format)
+	}
+
+	state.Shared.Update(func(st *state.State) {
+		if opts.AccountType != "" {
+			st.AccountType = opts.AccountType
+		}
+	})
+
 	if err := paths.EnsurePaths(paths.Default); err != nil {
 		return err
 	}
 
-	if err := token.SetupGitHubToken(ctx, state.Shared, paths.Default, token.SetupGitHubTokenOptions{}, http.DefaultClient); err != nil {
+	if _, err := bootstrapAuth(ctx, bootstrapAuthOptions{Client: http.DefaultClient, GitHubToken: opts.GitHubToken}); err != nil {
 		return err
 	}
 
-	usage, err := github.GetCopilotUsage(ctx, state.Shared, http.DefaultClient)
-	if err != nil {
-		return err
+	if opts.Watch <= 0 {
+		usage, err := github.GetCopilotUsage(ctx, state.Shared, http.DefaultClient)
+		if err != nil {
+			return err
+		}
+		return writeUsage(os.Stdout, usage, format)
 	}
 
-	premium := usage.QuotaSnapshots.PremiumInteractions
-	premiumTotal := premium.Entitlement
-	premiumUsed := premiumTotal - premium.Remaining
-	premiumPercentUsed := percentage(premiumUsed, premiumTotal)
-	premiumPercentRemaining := premium.PercentRemaining
-
-	logger.Info("Copilot Usage (plan: %s)", usage.CopilotPlan)
-	logger.Info("Quota resets: %s", usage.QuotaResetDate)
-	logger.Info("\nQuotas:")
-	logger.Info(
-		"  Premium: %s/%s used (%.1f%% used, %.1f%% remaining)",
-		formatCount(premiumUsed),
-		formatCount(premiumTotal),
-		premiumPercentUsed,
-		premiumPercentRemaining,
-	)
-	logQuota("Chat", usage.QuotaSnapshots.Chat)
-	logQuota("Completions", usage.QuotaSnapshots.Completions)
+	ticker := time.NewTicker(opts.Watch)
+	defer ticker.Stop()
+	for {
+		usage, err := github.GetCopilotUsage(ctx, state.Shared, http.DefaultClient)
+		if ctx.Err() != nil {
+			return nil
+		}
+		redraw := format == "text" || format == "table"
+		if redraw {
+			// Move to the top left and clear the screen.
+			fmt.Print("\033[H\033[2J")
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		} else if err := writeUsage(os.Stdout, usage, format); err != nil {
+			return err
+		}
+		if redraw {
+			fmt.Printf("\nUpdated %s, refreshing every %s (Ctrl+C to stop)\n", time.Now().Format("15:04:05"), opts.Watch)
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+func writeUsage(w io.Writer, usage *github.CopilotUsageResponse, format string) error {
+	switch format {
+	case "json":
+		// One compact document per line so --watch output can be streamed.
+		return json.NewEncoder(w).Encode(usage)
+	case "csv":
+		return writeUsageCSV(w, usage)
+	case "table":
+		return writeUsageTable(w, usage)
+	}
 
+	fmt.Fprintf(w, "Copilot Usage (plan: %s)\n", usage.CopilotPlan)
+	fmt.Fprintf(w, "Quota resets: %s\n", usage.QuotaResetDate)
+	fmt.Fprintln(w, "\nQuotas:")
+	for _, quota := range usage.QuotaSnapshots.All() {
+		if quota.Unlimited {
+			fmt.Fprintf(w, "  %s: unlimited\n", quotaLabel(quota.Name))
+			continue
+		}
+		used := quotaUsed(quota.QuotaDetail)
+		fmt.Fprintf(
+			w,
+			"  %s: %s/%s used (%.1f%% used, %.1f%% remaining)\n",
+			quotaLabel(quota.Name),
+			formatCount(used),
+			formatCount(quota.Entitlement),
+			percentage(used, quota.Entitlement),
+			quota.PercentRemaining,
+		)
+	}
 	return nil
 }
 
-func logQuota(name string, detail github.QuotaDetail) {
-	total := detail.Entitlement
-	used := total - detail.Remaining
-	logger.Info(
-		"  %s: %s/%s used (%.1f%% used, %.1f%% remaining)",
-		name,
-		formatCount(used),
-		formatCount(total),
-		percentage(used, total),
-		detail.PercentRemaining,
-	)
+func writeUsageTable(w io.Writer, usage *github.CopilotUsageResponse) error {
+	fmt.Fprintf(w, "Plan: %s  Resets: %s\n\n", usage.CopilotPlan, usage.QuotaResetDate)
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "QUOTA\tUSED\tENTITLEMENT\tREMAINING\t% USED\t% REMAINING\tOVERAGE")
+	for _, quota := range usage.QuotaSnapshots.All() {
+		overage := "-"
+		if quota.OveragePermitted {
+			overage = formatCount(quota.OverageCount)
+		}
+		if quota.Unlimited {
+			fmt.Fprintf(tw, "%s\t-\tunlimited\tunlimited\t-\t-\t%s\n", quota.Name, overage)
+			continue
+		}
+		used := quotaUsed(quota.QuotaDetail)
+		fmt.Fprintf(
+			tw,
+			"%s\t%s\t%s\t%s\t%.1f\t%.1f\t%s\n",
+			quota.Name,
+			formatCount(used),
+			formatCount(quota.Entitlement),
+			formatCount(quota.Remaining),
+			percentage(used, quota.Entitlement),
+			quota.PercentRemaining,
+			overage,
+		)
+	}
+	return tw.Flush()
+}
+
+func writeUsageCSV(w io.Writer, usage *github.CopilotUsageResponse) error {
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"plan", "reset_date", "quota", "unlimited", "entitlement", "used", "remaining", "percent_used", "percent_remaining", "overage_permitted", "overage_count"})
+	for _, quota := range usage.QuotaSnapshots.All() {
+		row := []string{usage.CopilotPlan, usage.QuotaResetDate, quota.Name, strconv.FormatBool(quota.Unlimited)}
+		if quota.Unlimited {
+			row = append(row, "", "", "", "", "")
+		} else {
+			used := quotaUsed(quota.QuotaDetail)
+			row = append(row,
+				formatCount(quota.Entitlement),
+				formatCount(used),
+				formatCount(quota.Remaining),
+				strconv.FormatFloat(percentage(used, quota.Entitlement), 'f', 1, 64),
+				strconv.FormatFloat(quota.PercentRemaining, 'f', 1, 64),
+			)
+		}
+		row = append(row, strconv.FormatBool(quota.OveragePermitted), formatCount(quota.OverageCount))
+		cw.Write(row)
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+func quotaLabel(name string) string {
+	switch name {
+	case "premium_interactions":
+		return "Premium"
+	case "chat":
+		return "Chat"
+	case "completions":
+		return "Completions"
+	}
+	return name
+}
+
+func quotaUsed(detail github.QuotaDetail) float64 {
+	return max(detail.Entitlement-detail.Remaining, 0)
 }
 
 func percentage(value, total float64) float64 {
@@ -0,0 +1,202 @@
+package batches
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"internal/logger"
+)
+
+// MaxFileBytes is the largest upload accepted by the files store.
+const MaxFileBytes = 512 << 20
+
+var ErrFileNotFound = errors.New("file not found")
+
+// File mirrors the OpenAI file object.
+type File struct {
+	ID        string `json:"id"`
+	Object    string `json:"object"`
+	Bytes     int64  `json:"bytes"`
+	CreatedAt int64  `json:"created_at"`
+	Filename  string `json:"filename"`
+	Purpose   string `json:"purpose"`
+	Status    string `json:"status"`
+}
+
+// fileRecord is the stored metadata of a file: the file object and the API
+// key that owns it.
+type fileRecord struct {
+	File
+	Owner string `json:"owner,omitempty"`
+}
+
+// Files is a minimal OpenAI-compatible file store in the app directory.
+// Files are only visible to the API key that created them.
+type Files struct {
+	dir string
+
+	mu    sync.Mutex
+	files map[string]fileRecord
+}
+
+func NewFiles(dir string) (*Files, error) {
+	f := &Files{dir: dir, files: make(map[string]fileRecord)}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return f, nil
+		}
+		return nil, err
+	}
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasSuffix(name, ".json") || strings.HasPrefix(name, ".") {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			return nil, err
+		}
+		var rec fileRecord
+		if err := json.Unmarshal(data, &rec); err != nil {
+			logger.Warn("Skipping unreadable file metadata %s: %v", name, err)
+			continue
+		}
+		f.files[rec.ID] = rec
+	}
+	return f, nil
+}
+
+// Create stores the content of r under a new file ID owned by owner.
+func (f *Files) Create(owner, filename, purpose string, r io.Reader) (File, error) {
+	tmp, err := os.CreateTemp(f.dir, ".upload-*")
+	if err != nil {
+		return File{}, err
+	}
+	size, err := io.Copy(tmp, io.LimitReader(r, MaxFileBytes+1))
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil && size > MaxFileBytes {
+		err = errors.New("file exceeds the maximum size of 512 MB")
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return File{}, err
+	}
+	return f.Import(owner, tmp.Name(), filename, purpose)
+}
+
+// Import moves an existing file at path into the store.
+func (f *Files) Import(owner, path, filename, purpose string) (File, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return File{}, err
+	}
+
+	file := File{
+		ID:        newID("file-"),
+		Object:    "file",
+		Bytes:     info.Size(),
+		CreatedAt: time.Now().Unix(),
+		Filename:  filename,
+		Purpose:   purpose,
+		Status:    "processed",
+	}
+	if err := os.Rename(path, f.contentPath(file.ID)); err != nil {
+		return File{}, err
+	}
+	rec := fileRecord{File: file, Owner: owner}
+	if err := writeJSONFile(f.metadataPath(file.ID), rec); err != nil {
+		os.Remove(f.contentPath(file.ID))
+		return File{}, err
+	}
+
+	f.mu.Lock()
+	f.files[file.ID] = rec
+	f.mu.Unlock()
+	return file, nil
+}
+
+func (f *Files) Get(id, owner string) (File, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	rec, ok := f.files[id]
+	if !ok || rec.Owner != owner {
+		return File{}, ErrFileNotFound
+	}
+	return rec.File, nil
+}
+
+// Open returns the content of a stored file; the caller closes it.
+func (f *Files) Open(id, owner string) (*os.File, error) {
+	if _, err := f.Get(id, owner); err != nil {
+		return nil, err
+	}
+	return os.Open(f.contentPath(id))
+}
+
+// List returns owner's files newest first, optionally filtered by purpose
+// and paginated after the given ID.
+func (f *Files) List(owner, purpose, after string, limit int) ([]File, bool) {
+	f.mu.Lock()
+	all := make([]File, 0, len(f.files))
+	for _, rec := range f.files {
+		if rec.Owner == owner && (purpose == "" || rec.Purpose == purpose) {
+			all = append(all, rec.File)
+		}
+	}
+	f.mu.Unlock()
+
+	sort.Slice(all, func(i, j int) bool {
+		if all[i].CreatedAt == all[j].CreatedAt {
+			return all[i].ID > all[j].ID
+		}
+		return all[i].CreatedAt > all[j].CreatedAt
+	})
+
+	if after != "" {
+		for i, file := range all {
+			if file.ID == after {
+				all = all[i+1:]
+				break
+			}
+		}
+	}
+	if len(all) > limit {
+		return all[:limit], true
+	}
+	return all, false
+}
+
+func (f *Files) Delete(id, owner string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if rec, ok := f.files[id]; !ok || rec.Owner != owner {
+		return ErrFileNotFound
+	}
+	if err := os.Remove(f.contentPath(id)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if err := os.Remove(f.metadataPath(id)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	delete(f.files, id)
+	return nil
+}
+
+func (f *Files) contentPath(id string) string {
+	return filepath.Join(f.dir, id+".data")
+}
+
+func (f *Files) metadataPath(id string) string {
+	return filepath.Join(f.dir, id+".json")
+}
@@ -0,0 +1,485 @@
+package batches
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"internal/fsutil"
+	"internal/logger"
+)
+
+const (
+	messageBatchTTL         = 24 * time.Hour
+	maxMessageBatchRequests = 100000
+)
+
+var (
+	ErrNotFound = errors.New("batch not found")
+	ErrNotEnded = errors.New("batch has not finished processing")
+)
+
+// MessageBatchRequest is one entry of an Anthropic Message Batch.
+type MessageBatchRequest struct {
+	CustomID string          `json:"custom_id"`
+	Params   json.RawMessage `json:"params"`
+}
+
+type MessageBatchRequestCounts struct {
+	Processing int `json:"processing"`
+	Succeeded  int `json:"succeeded"`
+	Errored    int `json:"errored"`
+	Canceled   int `json:"canceled"`
+	Expired    int `json:"expired"`
+}
+
+// MessageBatch mirrors the Anthropic Message Batch object.
+type MessageBatch struct {
+	ID                string                    `json:"id"`
+	Type              string                    `json:"type"`
+	ProcessingStatus  string                    `json:"processing_status"`
+	RequestCounts     MessageBatchRequestCounts `json:"request_counts"`
+	EndedAt           *time.Time                `json:"ended_at"`
+	CreatedAt         time.Time                 `json:"created_at"`
+	ExpiresAt         time.Time                 `json:"expires_at"`
+	ArchivedAt        *time.Time                `json:"archived_at"`
+	CancelInitiatedAt *time.Time                `json:"cancel_initiated_at"`
+	ResultsURL        *string                   `json:"results_url"`
+}
+
+// MessageBatchResult is one line of the results JSONL file.
+type MessageBatchResult struct {
+	CustomID string                 `json:"custom_id"`
+	Result   MessageBatchResultBody `json:"result"`
+}
+
+type MessageBatchResultBody struct {
+	Type    string          `json:"type"`
+	Message json.RawMessage `json:"message,omitempty"`
+	Error   json.RawMessage `json:"error,omitempty"`
+}
+
+// messageBatchRecord is persisted whenever the batch changes state. The
+// requests are written once to a separate file and results are appended to
+// the results file, from which the request counts are rebuilt on load.
+type messageBatchRecord struct {
+	Batch MessageBatch `json:"batch"`
+	Owner string       `json:"owner,omitempty"`
+
+	requests []MessageBatchRequest
+	done     map[string]bool
+	cancel   context.CancelFunc
+}
+
+// MessageBatches stores Anthropic Message Batches on disk and executes them
+// in the background.
+type MessageBatches struct {
+	dir  string
+	pool *Pool
+	exec Executor
+
+	mu      sync.Mutex
+	records map[string]*messageBatchRecord
+}
+
+// NewMessageBatches loads persisted batches from dir. Call Resume to continue
+// batches that were interrupted by a restart.
+func NewMessageBatches(dir string, pool *Pool, exec Executor) (*MessageBatches, error) {
+	m := &MessageBatches{
+		dir:     dir,
+		pool:    pool,
+		exec:    exec,
+		records: make(map[string]*messageBatchRecord),
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return m, nil
+		}
+		return nil, err
+	}
+
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasSuffix(name, ".json") || strings.HasPrefix(name, ".") {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			return nil, err
+		}
+		rec := &messageBatchRecord{}
+		if err := json.Unmarshal(data, rec); err != nil {
+			logger.Warn("Skipping unreadable message batch %s: %v", name, err)
+			continue
+		}
+		if rec.requests, err = fsutil.ReadJSONLines[MessageBatchRequest](m.requestsPath(rec.Batch.ID)); err != nil {
+			return nil, err
+		}
+		if err := m.loadResults(rec); err != nil {
+			return nil, err
+		}
+		m.records[rec.Batch.ID] = rec
+	}
+
+	return m, nil
+}
+
+// Resume restarts processing of batches that had not ended.
+func (m *MessageBatches) Resume() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, rec := range m.records {
+		if rec.Batch.ProcessingStatus == "ended" {
+			continue
+		}
+		logger.Info("Resuming message batch %s (%d remaining)", rec.Batch.ID, len(rec.requests)-len(rec.done))
+		m.start(rec)
+	}
+}
+
+// Create validates and persists a new batch and starts processing it.
+func (m *MessageBatches) Create(owner string, requests []MessageBatchRequest) (MessageBatch, error) {
+	if len(requests) == 0 {
+		return MessageBatch{}, fmt.Errorf("requests: at least one request is required")
+	}
+	if len(requests) > maxMessageBatchRequests {
+		return MessageBatch{}, fmt.Errorf("requests: at most %d requests are allowed", maxMessageBatchRequests)
+	}
+	seen := make(map[string]bool, len(requests))
+	for i, req := range requests {
+		if req.CustomID == "" {
+			return MessageBatch{}, fmt.Errorf("requests.%d.custom_id: field required", i)
+		}
+		if seen[req.CustomID] {
+			return MessageBatch{}, fmt.Errorf("requests.%d.custom_id: duplicate custom_id %q", i, req.CustomID)
+		}
+		seen[req.CustomID] = true
+		if len(req.Params) == 0 {
+			return MessageBatch{}, fmt.Errorf("requests.%d.params: field required", i)
+		}
+	}
+
+	now := time.Now().UTC()
+	rec := &messageBatchRecord{
+		Batch: MessageBatch{
+			ID:               newID("msgbatch_"),
+			Type:             "message_batch",
+			ProcessingStatus: "in_progress",
+			RequestCounts:    MessageBatchRequestCounts{Processing: len(requests)},
+			CreatedAt:        now,
+			ExpiresAt:        now.Add(messageBatchTTL),
+		},
+		Owner:    owner,
+		requests: requests,
+		done:     make(map[string]bool),
+	}
+
+	if err := fsutil.WriteJSONLines(m.requestsPath(rec.Batch.ID), requests); err != nil {
+		return MessageBatch{}, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.persist(rec); err != nil {
+		return MessageBatch{}, err
+	}
+	m.records[rec.Batch.ID] = rec
+	m.start(rec)
+	return rec.Batch, nil
+}
+
+// lookup returns batch id if it belongs to owner. It must be called
+// with m.mu held.
+func (m *MessageBatches) lookup(id, owner string) (*messageBatchRecord, error) {
+	rec, ok := m.records[id]
+	if !ok || rec.Owner != owner {
+		return nil, ErrNotFound
+	}
+	return rec, nil
+}
+
+func (m *MessageBatches) Get(id, owner string) (MessageBatch, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	rec, err := m.lookup(id, owner)
+	if err != nil {
+		return MessageBatch{}, err
+	}
+	return rec.Batch, nil
+}
+
+// List returns owner's batches newest first, paginated by before/after IDs.
+func (m *MessageBatches) List(owner, beforeID, afterID string, limit int) ([]MessageBatch, bool) {
+	m.mu.Lock()
+	all := make([]MessageBatch, 0, len(m.records))
+	for _, rec := range m.records {
+		if rec.Owner == owner {
+			all = append(all, rec.Batch)
+		}
+	}
+	m.mu.Unlock()
+
+	sort.Slice(all, func(i, j int) bool {
+		if all[i].CreatedAt.Equal(all[j].CreatedAt) {
+			return all[i].ID > all[j].ID
+		}
+		return all[i].CreatedAt.After(all[j].CreatedAt)
+	})
+
+	start, end := 0, len(all)
+	for i, batch := range all {
+		if afterID != "" && batch.ID == afterID {
+			start = i + 1
+		}
+		if beforeID != "" && batch.ID == beforeID {
+			end = i
+		}
+	}
+	if start > end {
+		return nil, false
+	}
+	page := all[start:end]
+	if beforeID != "" && afterID == "" {
+		if len(page) > limit {
+			return page[len(page)-limit:], true
+		}
+		return page, false
+	}
+	if len(page) > limit {
+		return page[:limit], true
+	}
+	return page, false
+}
+
+// Cancel stops a batch; requests that have not completed are reported as canceled.
+func (m *MessageBatches) Cancel(id, owner string) (MessageBatch, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	rec, err := m.lookup(id, owner)
+	if err != nil {
+		return MessageBatch{}, err
+	}
+	if rec.Batch.ProcessingStatus != "in_progress" {
+		return rec.Batch, nil
+	}
+	now := time.Now().UTC()
+	rec.Batch.ProcessingStatus = "canceling"
+	rec.Batch.CancelInitiatedAt = &now
+	if err := m.persist(rec); err != nil {
+		return MessageBatch{}, err
+	}
+	if rec.cancel != nil {
+		rec.cancel()
+	}
+	return rec.Batch, nil
+}
+
+// ResultsPath returns the JSONL results file of an ended batch.
+func (m *MessageBatches) ResultsPath(id, owner string) (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	rec, err := m.lookup(id, owner)
+	if err != nil {
+		return "", err
+	}
+	if rec.Batch.ProcessingStatus != "ended" {
+		return "", ErrNotEnded
+	}
+	return m.resultsPath(id), nil
+}
+
+// start must be called with m.mu held.
+func (m *MessageBatches) start(rec *messageBatchRecord) {
+	ctx, cancel := context.WithCancel(context.Background())
+	rec.cancel = cancel
+	if rec.Batch.ProcessingStatus == "canceling" {
+		cancel()
+	}
+	go m.run(ctx, rec)
+}
+
+func (m *MessageBatches) run(ctx context.Context, rec *messageBatchRecord) {
+	defer rec.cancel()
+
+	m.mu.Lock()
+	pending := make([]MessageBatchRequest, 0, len(rec.requests))
+	for _, req := range rec.requests {
+		if !rec.done[req.CustomID] {
+			pending = append(pending, req)
+		}
+	}
+	owner := rec.Owner
+	expiresAt := rec.Batch.ExpiresAt
+	m.mu.Unlock()
+
+	expireCtx, cancelExpire := context.WithDeadline(ctx, expiresAt)
+	defer cancelExpire()
+
+	m.pool.Each(expireCtx, len(pending), func(i int) {
+		req := pending[i]
+		status, body := m.exec(expireCtx, owner, req.Params)
+		if expireCtx.Err() != nil {
+			// Leave it for finish to report as canceled or expired.
+			return
+		}
+		m.record(rec, req.CustomID, messageResultBody(status, body))
+	})
+
+	m.finish(rec)
+}
+
+// record appends a result. The record file is not rewritten, as the
+// counts are rebuilt from the results file on load.
+func (m *MessageBatches) record(rec *messageBatchRecord, customID string, result MessageBatchResultBody) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.recordLocked(rec, customID, result)
+}
+
+func (m *MessageBatches) recordLocked(rec *messageBatchRecord, customID string, result MessageBatchResultBody) {
+	if rec.done[customID] {
+		return
+	}
+	if err := fsutil.AppendJSONLine(m.resultsPath(rec.Batch.ID), MessageBatchResult{CustomID: customID, Result: result}); err != nil {
+		logger.Error("Failed to write result for message batch %s: %v", rec.Batch.ID, err)
+		return
+	}
+	rec.done[customID] = true
+	countResult(&rec.Batch.RequestCounts, result.Type)
+}
+
+// countResult moves one request from processing to the count of its result
+// type.
+func countResult(counts *MessageBatchRequestCounts, resultType string) {
+	counts.Processing--
+	switch resultType {
+	case "succeeded":
+		counts.Succeeded++
+	case "errored":
+		counts.Errored++
+	case "canceled":
+		counts.Canceled++
+	case "expired":
+		counts.Expired++
+	}
+}
+
+func (m *MessageBatches) finish(rec *messageBatchRecord) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	remaining := "expired"
+	if rec.Batch.CancelInitiatedAt != nil {
+		remaining = "canceled"
+	}
+	for _, req := range rec.requests {
+		if !rec.done[req.CustomID] {
+			m.recordLocked(rec, req.CustomID, MessageBatchResultBody{Type: remaining})
+		}
+	}
+
+	now := time.Now().UTC()
+	resultsURL := fmt.Sprintf("/v1/messages/batches/%s/results", rec.Batch.ID)
+	rec.Batch.ProcessingStatus = "ended"
+	rec.Batch.EndedAt = &now
+	rec.Batch.ResultsURL = &resultsURL
+	if err := m.persist(rec); err != nil {
+		logger.Error("Failed to persist message batch %s: %v", rec.Batch.ID, err)
+	}
+	logger.Info("Message batch %s ended: %d succeeded, %d errored, %d canceled, %d expired",
+		rec.Batch.ID, rec.Batch.RequestCounts.Succeeded, rec.Batch.RequestCounts.Errored,
+		rec.Batch.RequestCounts.Canceled, rec.Batch.RequestCounts.Expired)
+}
+
+func (m *MessageBatches) persist(rec *messageBatchRecord) error {
+	return writeJSONFile(filepath.Join(m.dir, rec.Batch.ID+".json"), rec)
+}
+
+func (m *MessageBatches) resultsPath(id string) string {
+	return filepath.Join(m.dir, id+".results.jsonl")
+}
+
+func (m *MessageBatches) requestsPath(id string) string {
+	return filepath.Join(m.dir, id+".requests.jsonl")
+}
+
+// loadResults rebuilds the completed requests and the request counts of rec
+// from its results file.
+func (m *MessageBatches) loadResults(rec *messageBatchRecord) error {
+	results, err := fsutil.ReadJSONLines[MessageBatchResult](m.resultsPath(rec.Batch.ID))
+	if err != nil {
+		return err
+	}
+	rec.done = make(map[string]bool, len(results))
+	rec.Batch.RequestCounts = MessageBatchRequestCounts{Processing: len(rec.requests)}
+	for _, result := range results {
+		if result.CustomID == "" || rec.done[result.CustomID] {
+			continue
+		}
+		rec.done[result.CustomID] = true
+		countResult(&rec.Batch.RequestCounts, result.Result.Type)
+	}
+	return nil
+}
+
+// messageResultBody converts a /v1/messages response into a batch result.
+func messageResultBody(status int, body json.RawMessage) MessageBatchResultBody {
+	if status >= 200 && status < 300 {
+		return MessageBatchResultBody{Type: "succeeded", Message: body}
+	}
+
+	var envelope struct {
+		Type  string          `json:"type"`
+		Error json.RawMessage `json:"error"`
+	}
+	if err := json.Unmarshal(body, &envelope); err == nil && envelope.Type == "error" && len(envelope.Error) > 0 {
+		return MessageBatchResultBody{Type: "errored", Error: body}
+	}
+
+	errorType := "api_error"
+	if status >= 400 && status < 500 {
+		errorType = "invalid_request_error"
+	}
+	message := errorMessage(body)
+	if message == "" {
+		message = http.StatusText(status)
+	}
+	data, _ := json.Marshal(map[string]any{
+		"type": "error",
+		"error": map[string]any{
+			"type":    errorType,
+			"message": message,
+		},
+	})
+	return MessageBatchResultBody{Type: "errored", Error: data}
+}
+
+// errorMessage extracts a human-readable message from an error body.
+func errorMessage(body json.RawMessage) string {
+	var envelope struct {
+		Error json.RawMessage `json:"error"`
+	}
+	if err := json.Unmarshal(body, &envelope); err == nil && len(envelope.Error) > 0 {
+		var detail struct {
+			Message string `json:"message"`
+		}
+		if err := json.Unmarshal(envelope.Error, &detail); err == nil && detail.Message != "" {
+			return detail.Message
+		}
+		var text string
+		if err := json.Unmarshal(envelope.Error, &text); err == nil {
+			return text
+		}
+	}
+	return strings.TrimSpace(string(body))
+}
@@ -0,0 +1,583 @@
+package batches
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"internal/fsutil"
+	"internal/logger"
+)
+
+const (
+	batchCompletionWindow = "24h"
+	batchTTL              = 24 * time.Hour
+	maxBatchRequests      = 50000
+	maxBatchLineBytes     = 10 << 20
+)
+
+var ErrBatchNotFound = errors.New("batch not found")
+
+type BatchRequestCounts struct {
+	Total     int `json:"total"`
+	Completed int `json:"completed"`
+	Failed    int `json:"failed"`
+}
+
+type BatchErrors struct {
+	Object string       `json:"object"`
+	Data   []BatchError `json:"data"`
+}
+
+type BatchError struct {
+	Code    string  `json:"code"`
+	Message string  `json:"message"`
+	Param   *string `json:"param"`
+	Line    *int    `json:"line"`
+}
+
+// Batch mirrors the OpenAI batch object.
+type Batch struct {
+	ID               string             `json:"id"`
+	Object           string             `json:"object"`
+	Endpoint         string             `json:"endpoint"`
+	Errors           *BatchErrors       `json:"errors"`
+	InputFileID      string             `json:"input_file_id"`
+	CompletionWindow string             `json:"completion_window"`
+	Status           string             `json:"status"`
+	OutputFileID     *string            `json:"output_file_id"`
+	ErrorFileID      *string            `json:"error_file_id"`
+	CreatedAt        int64              `json:"created_at"`
+	InProgressAt     *int64             `json:"in_progress_at"`
+	ExpiresAt        int64              `json:"expires_at"`
+	FinalizingAt     *int64             `json:"finalizing_at"`
+	CompletedAt      *int64             `json:"completed_at"`
+	FailedAt         *int64             `json:"failed_at"`
+	ExpiredAt        *int64             `json:"expired_at"`
+	CancellingAt     *int64             `json:"cancelling_at"`
+	CancelledAt      *int64             `json:"cancelled_at"`
+	RequestCounts    BatchRequestCounts `json:"request_counts"`
+	Metadata         map[string]string  `json:"metadata"`
+}
+
+type BatchCreateParams struct {
+	InputFileID      string            `json:"input_file_id"`
+	Endpoint         string            `json:"endpoint"`
+	CompletionWindow string            `json:"completion_window"`
+	Metadata         map[string]string `json:"metadata"`
+}
+
+// BatchInputLine is one request of a batch input file.
+type BatchInputLine struct {
+	CustomID string          `json:"custom_id"`
+	Method   string          `json:"method"`
+	URL      string          `json:"url"`
+	Body     json.RawMessage `json:"body"`
+}
+
+// BatchOutputLine is one line of a batch output or error file.
+type BatchOutputLine struct {
+	ID       string               `json:"id"`
+	CustomID string               `json:"custom_id"`
+	Response *BatchOutputResponse `json:"response"`
+	Error    *BatchLineError      `json:"error"`
+}
+
+type BatchOutputResponse struct {
+	StatusCode int             `json:"status_code"`
+	RequestID  string          `json:"request_id"`
+	Body       json.RawMessage `json:"body"`
+}
+
+type BatchLineError struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+type batchRecord struct {
+	Batch Batch  `json:"batch"`
+	Owner string `json:"owner,omitempty"`
+
+	done   map[string]bool
+	cancel context.CancelFunc
+}
+
+// OpenAIBatches executes OpenAI batch input files against the proxy's own
+// endpoints and stores the output and error files in the files store.
+type OpenAIBatches struct {
+	dir       string
+	files     *Files
+	pool      *Pool
+	executors map[string]Executor
+
+	mu      sync.Mutex
+	records map[string]*batchRecord
+}
+
+// NewOpenAIBatches loads persisted batches from dir. executors maps each
+// supported endpoint, e.g. "/v1/chat/completions", to its executor.
+func NewOpenAIBatches(dir string, files *Files, pool *Pool, executors map[string]Executor) (*OpenAIBatches, error) {
+	b := &OpenAIBatches{
+		dir:       dir,
+		files:     files,
+		pool:      pool,
+		executors: executors,
+		records:   make(map[string]*batchRecord),
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return b, nil
+		}
+		return nil, err
+	}
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasSuffix(name, ".json") || strings.HasPrefix(name, ".") {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			return nil, err
+		}
+		var rec batchRecord
+		if err := json.Unmarshal(data, &rec); err != nil {
+			logger.Warn("Skipping unreadable batch %s: %v", name, err)
+			continue
+		}
+		if err := b.loadResults(&rec); err != nil {
+			return nil, err
+		}
+		b.records[rec.Batch.ID] = &rec
+	}
+	return b, nil
+}
+
+// Resume restarts processing of batches that had not reached a final state.
+func (b *OpenAIBatches) Resume() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for _, rec := range b.records {
+		switch rec.Batch.Status {
+		case "validating", "in_progress", "finalizing", "cancelling":
+			logger.Info("Resuming batch %s (%d of %d done)", rec.Batch.ID, len(rec.done), rec.Batch.RequestCounts.Total)
+			b.start(rec)
+		}
+	}
+}
+
+// Create validates the input file and starts the batch. Input errors produce
+// a batch with status "failed", as the OpenAI API does.
+func (b *OpenAIBatches) Create(owner string, params BatchCreateParams) (Batch, error) {
+	if _, ok := b.executors[params.Endpoint]; !ok {
+		return Batch{}, fmt.Errorf("endpoint: unsupported value %q", params.Endpoint)
+	}
+	if params.CompletionWindow == "" {
+		params.CompletionWindow = batchCompletionWindow
+	}
+	if params.CompletionWindow != batchCompletionWindow {
+		return Batch{}, fmt.Errorf("completion_window: only %q is supported", batchCompletionWindow)
+	}
+	if _, err := b.files.Get(params.InputFileID, owner); err != nil {
+		return Batch{}, fmt.Errorf("input_file_id: %w", err)
+	}
+
+	now := time.Now()
+	rec := &batchRecord{
+		Batch: Batch{
+			ID:               newID("batch_"),
+			Object:           "batch",
+			Endpoint:         params.Endpoint,
+			InputFileID:      params.InputFileID,
+			CompletionWindow: params.CompletionWindow,
+			Status:           "validating",
+			CreatedAt:        now.Unix(),
+			ExpiresAt:        now.Add(batchTTL).Unix(),
+			Metadata:         params.Metadata,
+		},
+		Owner: owner,
+		done:  make(map[string]bool),
+	}
+
+	lines, validationErrors, err := b.readInput(rec.Batch, owner)
+	if err != nil {
+		return Batch{}, err
+	}
+	if len(validationErrors) > 0 {
+		failed := now.Unix()
+		rec.Batch.Status = "failed"
+		rec.Batch.FailedAt = &failed
+		rec.Batch.Errors = &BatchErrors{Object: "list", Data: validationErrors}
+	} else {
+		started := now.Unix()
+		rec.Batch.Status = "in_progress"
+		rec.Batch.InProgressAt = &started
+		rec.Batch.RequestCounts.Total = len(lines)
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if err := b.persist(rec); err != nil {
+		return Batch{}, err
+	}
+	b.records[rec.Batch.ID] = rec
+	if rec.Batch.Status == "in_progress" {
+		b.start(rec)
+	}
+	return rec.Batch, nil
+}
+
+// lookup returns batch id if it belongs to owner. It must be called with
+// b.mu held.
+func (b *OpenAIBatches) lookup(id, owner string) (*batchRecord, error) {
+	rec, ok := b.records[id]
+	if !ok || rec.Owner != owner {
+		return nil, ErrBatchNotFound
+	}
+	return rec, nil
+}
+
+func (b *OpenAIBatches) Get(id, owner string) (Batch, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	rec, err := b.lookup(id, owner)
+	if err != nil {
+		return Batch{}, err
+	}
+	return rec.Batch, nil
+}
+
+// List returns owner's batches newest first, paginated after the given ID.
+func (b *OpenAIBatches) List(owner, after string, limit int) ([]Batch, bool) {
+	b.mu.Lock()
+	all := make([]Batch, 0, len(b.records))
+	for _, rec := range b.records {
+		if rec.Owner == owner {
+			all = append(all, rec.Batch)
+		}
+	}
+	b.mu.Unlock()
+
+	sort.Slice(all, func(i, j int) bool {
+		if all[i].CreatedAt == all[j].CreatedAt {
+			return all[i].ID > all[j].ID
+		}
+		return all[i].CreatedAt > all[j].CreatedAt
+	})
+	if after != "" {
+		for i, batch := range all {
+			if batch.ID == after {
+				all = all[i+1:]
+				break
+			}
+		}
+	}
+	if len(all) > limit {
+		return all[:limit], true
+	}
+	return all, false
+}
+
+// Cancel stops a running batch. Completed requests are kept in the output file.
+func (b *OpenAIBatches) Cancel(id, owner string) (Batch, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	rec, err := b.lookup(id, owner)
+	if err != nil {
+		return Batch{}, err
+	}
+	if rec.Batch.Status != "validating" && rec.Batch.Status != "in_progress" {
+		return rec.Batch, nil
+	}
+	now := time.Now().Unix()
+	rec.Batch.Status = "cancelling"
+	rec.Batch.CancellingAt = &now
+	if err := b.persist(rec); err != nil {
+		return Batch{}, err
+	}
+	if rec.cancel != nil {
+		rec.cancel()
+	}
+	return rec.Batch, nil
+}
+
+// readInput parses the batch input file, collecting per-line validation errors.
+func (b *OpenAIBatches) readInput(batch Batch, owner string) ([]BatchInputLine, []BatchError, error) {
+	file, err := b.files.Open(batch.InputFileID, owner)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer file.Close()
+
+	var (
+		lines    []BatchInputLine
+		failures []BatchError
+	)
+	seen := make(map[string]bool)
+	fail := func(line int, code, message string) {
+		failure := BatchError{Code: code, Message: message}
+		if line > 0 {
+			failure.Line = &line
+		}
+		failures = append(failures, failure)
+	}
+
+	reader := bufio.NewReaderSize(file, 64*1024)
+	for lineNo := 1; ; lineNo++ {
+		raw, readErr := reader.ReadBytes('\n')
+		if len(raw) > maxBatchLineBytes {
+			fail(lineNo, "invalid_request", "line exceeds the maximum size")
+		} else if trimmed := bytes.TrimSpace(raw); len(trimmed) > 0 {
+			var line BatchInputLine
+			switch {
+			case json.Unmarshal(trimmed, &line) != nil:
+				fail(lineNo, "invalid_json_line", "line is not valid JSON")
+			case line.CustomID == "":
+				fail(lineNo, "missing_required_parameter", "custom_id is required")
+			case seen[line.CustomID]:
+				fail(lineNo, "duplicate_custom_id", fmt.Sprintf("custom_id %q is not unique", line.CustomID))
+			case line.Method != "" && line.Method != http.MethodPost:
+				fail(lineNo, "invalid_method", "only POST requests are supported")
+			case line.URL != batch.Endpoint:
+				fail(lineNo, "mismatched_endpoint", fmt.Sprintf("url %q does not match the batch endpoint %q", line.URL, batch.Endpoint))
+			case len(line.Body) == 0:
+				fail(lineNo, "missing_required_parameter", "body is required")
+			default:
+				seen[line.CustomID] = true
+				lines = append(lines, line)
+			}
+		}
+		if readErr != nil {
+			break
+		}
+	}
+
+	if len(failures) == 0 && len(lines) == 0 {
+		fail(0, "empty_file", "the input file contains no requests")
+	}
+	if len(lines) > maxBatchRequests {
+		fail(0, "too_many_requests", fmt.Sprintf("a batch may contain at most %d requests", maxBatchRequests))
+	}
+	return lines, failures, nil
+}
+
+// start must be called with b.mu held.
+func (b *OpenAIBatches) start(rec *batchRecord) {
+	ctx, cancel := context.WithCancel(context.Background())
+	rec.cancel = cancel
+	if rec.Batch.Status == "cancelling" {
+		cancel()
+	}
+	go b.run(ctx, rec)
+}
+
+func (b *OpenAIBatches) run(ctx context.Context, rec *batchRecord) {
+	defer rec.cancel()
+
+	b.mu.Lock()
+	batch := rec.Batch
+	owner := rec.Owner
+	b.mu.Unlock()
+
+	lines, _, err := b.readInput(batch, owner)
+	if err != nil {
+		logger.Error("Failed to read input of batch %s: %v", batch.ID, err)
+		b.fail(rec, BatchError{Code: "input_file_unavailable", Message: fmt.Sprintf("The input file could not be read: %v", err)})
+		return
+	}
+
+	b.mu.Lock()
+	pending := make([]BatchInputLine, 0, len(lines))
+	for _, line := range lines {
+		if !rec.done[line.CustomID] {
+			pending = append(pending, line)
+		}
+	}
+	b.mu.Unlock()
+
+	exec := b.executors[batch.Endpoint]
+	expireCtx, cancelExpire := context.WithDeadline(ctx, time.Unix(batch.ExpiresAt, 0))
+	defer cancelExpire()
+
+	b.pool.Each(expireCtx, len(pending), func(i int) {
+		line := pending[i]
+		status, body := exec(expireCtx, owner, line.Body)
+		if expireCtx.Err() != nil {
+			return
+		}
+		b.record(rec, BatchOutputLine{
+			ID:       newID("batch_req_"),
+			CustomID: line.CustomID,
+			Response: &BatchOutputResponse{
+				StatusCode: status,
+				RequestID:  newID("req_"),
+				Body:       body,
+			},
+		})
+	})
+
+	b.finish(rec, lines)
+}
+
+// record appends the result of one request to the output or error file.
+// The record itself is only persisted on state changes; its counts are
+// rebuilt from these files on load.
+func (b *OpenAIBatches) record(rec *batchRecord, line BatchOutputLine) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.recordLocked(rec, line)
+}
+
+func (b *OpenAIBatches) recordLocked(rec *batchRecord, line BatchOutputLine) {
+	if rec.done[line.CustomID] {
+		return
+	}
+	succeeded := line.Error == nil && line.Response != nil && line.Response.StatusCode >= 200 && line.Response.StatusCode < 300
+	path := b.errorPath(rec.Batch.ID)
+	if succeeded {
+		path = b.outputPath(rec.Batch.ID)
+	}
+	if err := fsutil.AppendJSONLine(path, line); err != nil {
+		logger.Error("Failed to write result for batch %s: %v", rec.Batch.ID, err)
+		return
+	}
+	rec.done[line.CustomID] = true
+	if succeeded {
+		rec.Batch.RequestCounts.Completed++
+	} else {
+		rec.Batch.RequestCounts.Failed++
+	}
+}
+
+// loadResults rebuilds the finished requests and their counts from the
+// output and error files.
+func (b *OpenAIBatches) loadResults(rec *batchRecord) error {
+	rec.done = make(map[string]bool)
+	counts := &rec.Batch.RequestCounts
+	counts.Completed, counts.Failed = 0, 0
+	for _, file := range []struct {
+		path  string
+		count *int
+	}{
+		{b.outputPath(rec.Batch.ID), &counts.Completed},
+		{b.errorPath(rec.Batch.ID), &counts.Failed},
+	} {
+		results, err := fsutil.ReadJSONLines[BatchOutputLine](file.path)
+		if err != nil {
+			return err
+		}
+		for _, result := range results {
+			if result.CustomID == "" || rec.done[result.CustomID] {
+				continue
+			}
+			rec.done[result.CustomID] = true
+			*file.count++
+		}
+	}
+	return nil
+}
+
+func (b *OpenAIBatches) finish(rec *batchRecord, lines []BatchInputLine) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := time.Now().Unix()
+	final := "completed"
+	code, message := "", ""
+	switch {
+	case rec.Batch.CancellingAt != nil:
+		final = "cancelled"
+		code, message = "batch_cancelled", "This request was not executed because the batch was cancelled."
+	case now >= rec.Batch.ExpiresAt:
+		final = "expired"
+		code, message = "batch_expired", "This request could not be executed before the completion window expired."
+	}
+	if code != "" {
+		for _, line := range lines {
+			if !rec.done[line.CustomID] {
+				b.recordLocked(rec, BatchOutputLine{
+					ID:       newID("batch_req_"),
+					CustomID: line.CustomID,
+					Error:    &BatchLineError{Code: code, Message: message},
+				})
+			}
+		}
+	}
+
+	if final == "completed" {
+		rec.Batch.FinalizingAt = &now
+	}
+	if id, ok := b.importResults(rec.Owner, b.outputPath(rec.Batch.ID), rec.Batch.ID+"_output.jsonl"); ok {
+		rec.Batch.OutputFileID = &id
+	}
+	if id, ok := b.importResults(rec.Owner, b.errorPath(rec.Batch.ID), rec.Batch.ID+"_error.jsonl"); ok {
+		rec.Batch.ErrorFileID = &id
+	}
+
+	rec.Batch.Status = final
+	switch final {
+	case "completed":
+		rec.Batch.CompletedAt = &now
+	case "cancelled":
+		rec.Batch.CancelledAt = &now
+	case "expired":
+		rec.Batch.ExpiredAt = &now
+	}
+	if err := b.persist(rec); err != nil {
+		logger.Error("Failed to persist batch %s: %v", rec.Batch.ID, err)
+	}
+	logger.Info("Batch %s %s: %d completed, %d failed", rec.Batch.ID, final,
+		rec.Batch.RequestCounts.Completed, rec.Batch.RequestCounts.Failed)
+}
+
+// fail ends a batch that cannot run with status "failed".
+func (b *OpenAIBatches) fail(rec *batchRecord, failure BatchError) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := time.Now().Unix()
+	rec.Batch.Status = "failed"
+	rec.Batch.FailedAt = &now
+	rec.Batch.Errors = &BatchErrors{Object: "list", Data: []BatchError{failure}}
+	if err := b.persist(rec); err != nil {
+		logger.Error("Failed to persist batch %s: %v", rec.Batch.ID, err)
+	}
+}
+
+// importResults moves a results file into the files store. It must be
+// called with b.mu held.
+func (b *OpenAIBatches) importResults(owner, path, filename string) (string, bool) {
+	info, err := os.Stat(path)
+	if err != nil || info.Size() == 0 {
+		return "", false
+	}
+	file, err := b.files.Import(owner, path, filename, "batch_output")
+	if err != nil {
+		logger.Error("Failed to store %s: %v", filename, err)
+		return "", false
+	}
+	return file.ID, true
+}
+
+func (b *OpenAIBatches) persist(rec *batchRecord) error {
+	return writeJSONFile(filepath.Join(b.dir, rec.Batch.ID+".json"), rec)
+}
+
+func (b *OpenAIBatches) outputPath(id string) string {
+	return filepath.Join(b.dir, id+".output.jsonl")
+}
+
+func (b *OpenAIBatches) errorPath(id string) string {
+	return filepath.Join(b.dir, id+".errors.jsonl")
+}
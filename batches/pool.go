@@ -0,0 +1,76 @@
+package batches
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"sync"
+
+	"internal/fsutil"
+)
+
+// Pool bounds the number of batch requests executing at once across all batches.
+type Pool struct {
+	slots chan struct{}
+}
+
+func NewPool(size int) *Pool {
+	if size < 1 {
+		size = 1
+	}
+	return &Pool{slots: make(chan struct{}, size)}
+}
+
+// Acquire blocks until a worker slot is free or ctx is done.
+func (p *Pool) Acquire(ctx context.Context) error {
+	select {
+	case p.slots <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (p *Pool) Release() {
+	<-p.slots
+}
+
+// Each runs fn for indexes 0..n-1, at most pool-size at a time, and waits for
+// the started calls to return. It stops starting new calls once ctx is done.
+func (p *Pool) Each(ctx context.Context, n int, fn func(i int)) {
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		if err := p.Acquire(ctx); err != nil {
+			break
+		}
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			defer p.Release()
+			fn(i)
+		}(i)
+	}
+	wg.Wait()
+}
+
+// Executor runs a single batch request against the proxy on behalf of owner
+// and returns the HTTP status and JSON body it produced.
+type Executor func(ctx context.Context, owner string, body json.RawMessage) (int, json.RawMessage)
+
+func newID(prefix string) string {
+	var b [12]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		panic(err)
+	}
+	return prefix + hex.EncodeToString(b[:])
+}
+
+// writeJSONFile replaces path atomically so a crash never leaves partial state.
+func writeJSONFile(path string, v any) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	return fsutil.WriteFileAtomic(path, data)
+}
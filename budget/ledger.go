@@ -0,0 +1,137 @@
+package budget
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"internal/fsutil"
+)
+
+// Spent is what a key has used in the current day and month, in premium
+// request units.
+type Spent struct {
+	Day     string  `json:"day"`
+	Daily   float64 `json:"daily"`
+	Month   string  `json:"month"`
+	Monthly float64 `json:"monthly"`
+}
+
+// Ledger tracks premium units charged to each API key for the current UTC
+// day and month, persisted to a JSON file after every change.
+type Ledger struct {
+	path string
+
+	mu    sync.Mutex
+	spent map[string]Spent
+}
+
+func NewLedger(path string) (*Ledger, error) {
+	l := &Ledger{path: path, spent: make(map[string]Spent)}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return l, nil
+		}
+		return nil, err
+	}
+	if len(data) > 0 {
+		if err := json.Unmarshal(data, &l.spent); err != nil {
+			return nil, err
+		}
+	}
+	return l, nil
+}
+
+// Spent returns what key has used so far in the day and month of now.
+func (l *Ledger) Spent(key string, now time.Time) Spent {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.current(key, now)
+}
+
+// Limits are a key's budgets in premium units; nil means no limit.
+type Limits struct {
+	Daily   *float64
+	Monthly *float64
+}
+
+// ExceededError reports a reservation that would take a key past one of its
+// budgets.
+type ExceededError struct {
+	// Period is "daily" or "monthly".
+	Period string
+	Spent  float64
+	Limit  float64
+}
+
+func (e *ExceededError) Error() string {
+	return fmt.Sprintf("%s budget of %g premium requests exceeded (%g used)", e.Period, e.Limit, e.Spent)
+}
+
+// Reserve charges units to key's day and month and saves the ledger, unless
+// that would take key past limits. Checking and charging happen under one
+// lock, so concurrent requests cannot overspend together.
+func (l *Ledger) Reserve(key string, units float64, limits Limits, now time.Time) (Spent, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	spent := l.current(key, now)
+	switch {
+	case limits.Daily != nil && spent.Daily+units > *limits.Daily:
+		return spent, &ExceededError{Period: "daily", Spent: spent.Daily, Limit: *limits.Daily}
+	case limits.Monthly != nil && spent.Monthly+units > *limits.Monthly:
+		return spent, &ExceededError{Period: "monthly", Spent: spent.Monthly, Limit: *limits.Monthly}
+	}
+	return l.addLocked(key, spent, units, true, true)
+}
+
+// Refund returns units reserved at reservedAt to the periods that have not
+// ended since.
+func (l *Ledger) Refund(key string, units float64, reservedAt, now time.Time) (Spent, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	spent := l.current(key, now)
+	reserved := l.periods(reservedAt)
+	return l.addLocked(key, spent, -units, spent.Day == reserved.Day, spent.Month == reserved.Month)
+}
+
+// addLocked adds units to the day and month totals selected and saves the
+// ledger. Nothing is written when there is nothing to add.
+func (l *Ledger) addLocked(key string, spent Spent, units float64, day, month bool) (Spent, error) {
+	if units == 0 || (!day && !month) {
+		return spent, nil
+	}
+	if day {
+		spent.Daily = max(spent.Daily+units, 0)
+	}
+	if month {
+		spent.Monthly = max(spent.Monthly+units, 0)
+	}
+	l.spent[key] = spent
+
+	data, err := json.MarshalIndent(l.spent, "", "  ")
+	if err != nil {
+		return spent, err
+	}
+	return spent, fsutil.WriteFileAtomic(l.path, data)
+}
+
+// periods returns the day and month that now falls in, with nothing spent.
+func (l *Ledger) periods(now time.Time) Spent {
+	return Spent{Day: now.UTC().Format("2006-01-02"), Month: now.UTC().Format("2006-01")}
+}
+
+// current returns key's totals, restarting any period that has ended.
+func (l *Ledger) current(key string, now time.Time) Spent {
+	period := l.periods(now)
+	spent := l.spent[key]
+	if spent.Day != period.Day {
+		spent.Day, spent.Daily = period.Day, 0
+	}
+	if spent.Month != period.Month {
+		spent.Month, spent.Monthly = period.Month, 0
+	}
+	return spent
+}
@@ -0,0 +1,228 @@
+package compaction
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"internal/config"
+	"internal/logger"
+	"internal/services/copilot"
+)
+
+const (
+	// charsPerToken is a rough heuristic; Copilot does not expose a tokenizer.
+	charsPerToken = 4
+	// messageOverhead approximates role and framing tokens per message.
+	messageOverhead = 4
+	// minToolOutputChars is the size below which tool outputs are left alone.
+	minToolOutputChars = 256
+	// summaryReserve is budgeted for the summary message before it exists.
+	summaryReserve = 512
+	// maxTranscriptEntryChars caps each message when building a summary prompt.
+	maxTranscriptEntryChars = 2000
+)
+
+// Summarizer condenses a transcript of dropped messages into a short summary.
+type Summarizer func(ctx context.Context, model string, transcript string) (string, error)
+
+type Options struct {
+	Mode         string
+	SummaryModel string
+	KeepRecent   int
+	Summarize    Summarizer
+}
+
+// Result reports what was removed from the conversation.
+type Result struct {
+	Mode               string
+	OriginalTokens     int
+	Tokens             int
+	TrimmedToolOutputs int
+	DroppedMessages    int
+}
+
+// DroppedTokens is the estimated number of prompt tokens removed.
+func (r Result) DroppedTokens() int {
+	if r.OriginalTokens <= r.Tokens {
+		return 0
+	}
+	return r.OriginalTokens - r.Tokens
+}
+
+// Compacted reports whether the payload was modified.
+func (r Result) Compacted() bool {
+	return r.TrimmedToolOutputs > 0 || r.DroppedMessages > 0
+}
+
+// EstimateTokens approximates the prompt size of a chat payload.
+func EstimateTokens(payload copilot.ChatCompletionsPayload) int {
+	total := estimateMessages(payload.Messages)
+	if len(payload.Tools) > 0 {
+		if data, err := json.Marshal(payload.Tools); err == nil {
+			total += len(data) / charsPerToken
+		}
+	}
+	return total
+}
+
+func estimateMessages(messages []copilot.Message) int {
+	total := 0
+	for _, msg := range messages {
+		total += estimateMessage(msg)
+	}
+	return total
+}
+
+func estimateMessage(msg copilot.Message) int {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return messageOverhead
+	}
+	return len(data)/charsPerToken + messageOverhead
+}
+
+// Compact shortens payload until its estimated prompt fits within limit. Old
+// tool outputs are trimmed first; if that is not enough, early turns are
+// replaced by a summary or a truncation note. Tool calls and their results are
+// never separated.
+func Compact(ctx context.Context, payload copilot.ChatCompletionsPayload, limit int, opts Options) (copilot.ChatCompletionsPayload, Result, error) {
+	result := Result{Mode: opts.Mode}
+	result.OriginalTokens = EstimateTokens(payload)
+	result.Tokens = result.OriginalTokens
+
+	if limit <= 0 || result.OriginalTokens <= limit {
+		return payload, result, nil
+	}
+
+	toolsTokens := result.OriginalTokens - estimateMessages(payload.Messages)
+	messages := append([]copilot.Message(nil), payload.Messages...)
+
+	keepRecent := opts.KeepRecent
+	if keepRecent < 1 {
+		keepRecent = 1
+	}
+	protectedFrom := len(messages) - keepRecent
+	if protectedFrom < 0 {
+		protectedFrom = 0
+	}
+
+	current := result.OriginalTokens
+	for i := 0; i < protectedFrom && current > limit; i++ {
+		if messages[i].Role != "tool" {
+			continue
+		}
+		text := messages[i].Content.Text()
+		if len(text) < minToolOutputChars {
+			continue
+		}
+		before := estimateMessage(messages[i])
+		note := fmt.Sprintf("[tool output truncated by proxy: %d characters omitted]", len(text))
+		messages[i].Content = copilot.MessageContent{StringValue: &note}
+		current += estimateMessage(messages[i]) - before
+		result.TrimmedToolOutputs++
+	}
+
+	if current > limit {
+		compacted, dropped, err := dropEarlyTurns(ctx, messages, limit-toolsTokens, opts)
+		if err != nil {
+			return payload, result, err
+		}
+		messages = compacted
+		result.DroppedMessages = dropped
+	}
+
+	payload.Messages = messages
+	result.Tokens = EstimateTokens(payload)
+	if result.Tokens > limit {
+		logger.Warn("Compaction could not fit conversation: ~%d tokens remain for a limit of %d", result.Tokens, limit)
+	}
+	return payload, result, nil
+}
+
+func dropEarlyTurns(ctx context.Context, messages []copilot.Message, limit int, opts Options) ([]copilot.Message, int, error) {
+	head := 0
+	for head < len(messages) && messages[head].Role == "system" {
+		head++
+	}
+	headTokens := estimateMessages(messages[:head])
+
+	// Take the earliest cut that fits; if none does, keep only the last user
+	// turn so the request still has the best chance of being answered.
+	cut := -1
+	for j := head + 1; j < len(messages); j++ {
+		if !isTurnBoundary(messages, j) {
+			continue
+		}
+		cut = j
+		if headTokens+summaryReserve+estimateMessages(messages[j:]) <= limit {
+			break
+		}
+	}
+	if cut == -1 {
+		return messages, 0, nil
+	}
+
+	dropped := messages[head:cut]
+	note := fmt.Sprintf("[%d earlier messages were omitted by the proxy to fit the model context window]", len(dropped))
+
+	if opts.Mode == config.CompactionSummarize && opts.Summarize != nil {
+		summary, err := opts.Summarize(ctx, opts.SummaryModel, buildTranscript(dropped))
+		if err != nil {
+			logger.Warn("Compaction summary failed, falling back to truncation: %v", err)
+		} else if strings.TrimSpace(summary) != "" {
+			note = "Summary of earlier conversation (condensed by the proxy):\n\n" + strings.TrimSpace(summary)
+		}
+	}
+
+	result := make([]copilot.Message, 0, head+1+len(messages)-cut)
+	result = append(result, messages[:head]...)
+	result = append(result, copilot.Message{
+		Role:    "system",
+		Content: copilot.MessageContent{StringValue: &note},
+	})
+	result = append(result, messages[cut:]...)
+	return result, len(dropped), nil
+}
+
+// isTurnBoundary reports whether the conversation can start at index j
+// without orphaning a tool result from the assistant call that produced it.
+func isTurnBoundary(messages []copilot.Message, j int) bool {
+	if messages[j].Role != "user" {
+		return false
+	}
+	calls := make(map[string]struct{})
+	for _, msg := range messages[j:] {
+		for _, call := range msg.ToolCalls {
+			calls[call.ID] = struct{}{}
+		}
+		if msg.Role == "tool" {
+			if msg.ToolCallID == nil {
+				continue
+			}
+			if _, ok := calls[*msg.ToolCallID]; !ok {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func buildTranscript(messages []copilot.Message) string {
+	var b strings.Builder
+	for _, msg := range messages {
+		text := msg.Content.Text()
+		for _, call := range msg.ToolCalls {
+			if text != "" {
+				text += "\n"
+			}
+			text += fmt.Sprintf("called %s(%s)", call.Function.Name, call.Function.Arguments)
+		}
+		if len(text) > maxTranscriptEntryChars {
+			text = text[:maxTranscriptEntryChars] + "…"
+		}
+		fmt.Fprintf(&b, "%s: %s\n\n", msg.Role, text)
+	}
+	return b.String()
+}
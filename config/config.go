@@ -0,0 +1,320 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+// Config mirrors the optional config.json stored in the app directory.
+type Config struct {
+	APIKeys    []APIKey         `json:"api_keys,omitempty"`
+	Compaction CompactionConfig `json:"compaction"`
+	// StructuredOutputs controls json_schema response_format handling.
+	StructuredOutputs StructuredOutputsConfig `json:"structured_outputs"`
+	// ResponseStore limits the local store behind stateful Responses requests.
+	ResponseStore ResponseStoreConfig `json:"response_store"`
+	// Upstream controls how requests are sent to Copilot.
+	Upstream UpstreamConfig `json:"upstream"`
+	// Streams controls keepalives and timeouts of streamed responses.
+	Streams StreamsConfig `json:"streams"`
+	// Fallbacks lists, per requested model, the models to try in order when
+	// it is rate limited, out of quota or failing.
+	Fallbacks map[string][]string `json:"fallbacks,omitempty"`
+	// Budgets controls how requests are charged against per-key budgets.
+	Budgets BudgetsConfig `json:"budgets"`
+	// Quota controls premium quota polling and what happens when it runs low.
+	Quota QuotaConfig `json:"quota"`
+	// Alerts posts to webhooks when quota or token health crosses a rule.
+	Alerts AlertsConfig `json:"alerts"`
+}
+
+// APIKey describes a named client key and the features enabled for it.
+type APIKey struct {
+	Name       string `json:"name"`
+	Key        string `json:"key"`
+	Compaction bool   `json:"compaction,omitempty"`
+	// DailyBudget and MonthlyBudget cap the premium request units the key
+	// may use per UTC day and month. Nil means unlimited.
+	DailyBudget   *float64 `json:"daily_budget,omitempty"`
+	MonthlyBudget *float64 `json:"monthly_budget,omitempty"`
+}
+
+// CompactionConfig controls how over-long conversations are shortened.
+type CompactionConfig struct {
+	// Mode is either "truncate" or "summarize".
+	Mode         string `json:"mode,omitempty"`
+	SummaryModel string `json:"summary_model,omitempty"`
+	// KeepRecent is the number of trailing messages that are never compacted.
+	KeepRecent int `json:"keep_recent,omitempty"`
+}
+
+// StructuredOutputsConfig controls validation of json_schema responses.
+type StructuredOutputsConfig struct {
+	// Retries is the number of extra attempts made when the output does not
+	// match the schema. Zero disables retries.
+	Retries *int `json:"retries,omitempty"`
+}
+
+// ResponseStoreConfig bounds how long and how many responses are kept.
+type ResponseStoreConfig struct {
+	TTLHours   int   `json:"ttl_hours,omitempty"`
+	MaxEntries int   `json:"max_entries,omitempty"`
+	MaxBytes   int64 `json:"max_bytes,omitempty"`
+}
+
+// UpstreamConfig controls how requests are sent to Copilot.
+type UpstreamConfig struct {
+	// ForceStream sends every request upstream with stream: true and
+	// reassembles the result for non-streaming clients.
+	ForceStream bool `json:"force_stream,omitempty"`
+	// ForceStreamModels forces streaming for these models only.
+	ForceStreamModels []string `json:"force_stream_models,omitempty"`
+	// MaxSSELineBytes bounds a single line of an upstream event stream.
+	// Zero means unbounded.
+	MaxSSELineBytes int `json:"max_sse_line_bytes,omitempty"`
+	// Retries is how often a request is retried after a connection error,
+	// a 5xx or a 429 with Retry-After. Zero disables retries.
+	Retries *int `json:"retries,omitempty"`
+	// RetryBackoffMillis is the first retry delay; it doubles per attempt.
+	RetryBackoffMillis int `json:"retry_backoff_ms,omitempty"`
+	// BreakerThreshold is the number of consecutive failures after which
+	// requests to a host and model fail fast. Zero disables the breaker.
+	BreakerThreshold *int `json:"breaker_threshold,omitempty"`
+	// BreakerCooldownSeconds is how long an open breaker waits before it
+	// lets a probe request through.
+	BreakerCooldownSeconds int `json:"breaker_cooldown_seconds,omitempty"`
+}
+
+// ForcesStream reports whether requests for model must be streamed upstream.
+func (u UpstreamConfig) ForcesStream(model string) bool {
+	if u.ForceStream {
+		return true
+	}
+	for _, candidate := range u.ForceStreamModels {
+		if candidate == model {
+			return true
+		}
+	}
+	return false
+}
+
+// BudgetsConfig controls how requests are charged against per-key budgets.
+type BudgetsConfig struct {
+	// Multipliers is the premium units one request to a model costs. Models
+	// not listed cost what the model list bills them, or nothing when they
+	// are not premium.
+	Multipliers map[string]float64 `json:"multipliers,omitempty"`
+	// ChargeAgentRequests also charges requests sent with X-Initiator: agent,
+	// which Copilot does not count as premium requests.
+	ChargeAgentRequests bool `json:"charge_agent_requests,omitempty"`
+}
+
+// QuotaConfig controls premium quota polling and what happens when it runs
+// low.
+type QuotaConfig struct {
+	// PollSeconds is how often Copilot usage is refreshed in the background.
+	// Zero disables polling.
+	PollSeconds *int `json:"poll_seconds,omitempty"`
+	// HistorySeconds is how often quota snapshots are appended to the usage
+	// history. Zero disables snapshots.
+	HistorySeconds *int `json:"history_seconds,omitempty"`
+	// MinPremiumRemaining is the number of remaining premium requests at or
+	// below which Action applies to premium models.
+	MinPremiumRemaining float64 `json:"min_premium_remaining,omitempty"`
+	// Action is QuotaRefuse or QuotaDowngrade; empty only reports the quota.
+	Action string `json:"action,omitempty"`
+	// Substitutes maps premium models to the model used instead when
+	// downgrading; DefaultSubstitute covers the rest.
+	Substitutes       map[string]string `json:"substitutes,omitempty"`
+	DefaultSubstitute string            `json:"default_substitute,omitempty"`
+	// PremiumModels marks models as premium in addition to those the model
+	// list reports.
+	PremiumModels []string `json:"premium_models,omitempty"`
+}
+
+// AlertsConfig lists alert rules and the webhooks they notify.
+type AlertsConfig struct {
+	Webhooks []Webhook   `json:"webhooks,omitempty"`
+	Rules    []AlertRule `json:"rules,omitempty"`
+	// CheckSeconds is how often rules are evaluated.
+	CheckSeconds int `json:"check_seconds,omitempty"`
+	// CooldownSeconds is the minimum time between two notifications of the
+	// same rule unless the rule sets its own.
+	CooldownSeconds *int `json:"cooldown_seconds,omitempty"`
+}
+
+// Webhook is an endpoint alerts are POSTed to.
+type Webhook struct {
+	Name string `json:"name,omitempty"`
+	URL  string `json:"url"`
+	// Format is WebhookSlack or WebhookGeneric (the default).
+	Format  string            `json:"format,omitempty"`
+	Headers map[string]string `json:"headers,omitempty"`
+}
+
+// AlertRule fires while its metric is below Below or at least AtLeast.
+type AlertRule struct {
+	Name string `json:"name"`
+	// Metric is one of the Alert* metric constants.
+	Metric string `json:"metric"`
+	// Quota selects the quota for quota metrics; it defaults to
+	// premium_interactions.
+	Quota   string   `json:"quota,omitempty"`
+	Below   *float64 `json:"below,omitempty"`
+	AtLeast *float64 `json:"at_least,omitempty"`
+	// CooldownSeconds overrides AlertsConfig.CooldownSeconds.
+	CooldownSeconds *int `json:"cooldown_seconds,omitempty"`
+	// Webhooks names the webhooks to notify; empty notifies all of them.
+	Webhooks []string `json:"webhooks,omitempty"`
+}
+
+// StreamsConfig controls keepalives and timeouts of streamed responses.
+type StreamsConfig struct {
+	// HeartbeatSeconds is how long a client stream may stay silent before a
+	// keepalive is sent. Zero disables heartbeats.
+	HeartbeatSeconds *int `json:"heartbeat_seconds,omitempty"`
+	// IdleTimeoutSeconds aborts a stream when upstream sends nothing for
+	// this long. Zero disables the timeout.
+	IdleTimeoutSeconds *int `json:"idle_timeout_seconds,omitempty"`
+}
+
+const (
+	QuotaRefuse    = "refuse"
+	QuotaDowngrade = "downgrade"
+
+	WebhookSlack   = "slack"
+	WebhookGeneric = "generic"
+
+	AlertQuotaRemaining        = "quota_remaining"
+	AlertQuotaRemainingPercent = "quota_remaining_percent"
+	AlertTokenRefreshFailures  = "token_refresh_failures"
+
+	CompactionTruncate  = "truncate"
+	CompactionSummarize = "summarize"
+
+	defaultSummaryModel = "gpt-4o-mini"
+	defaultKeepRecent   = 6
+
+	defaultStructuredOutputRetries = 2
+
+	defaultResponseStoreTTLHours   = 30 * 24
+	defaultResponseStoreMaxEntries = 1000
+	defaultResponseStoreMaxBytes   = 256 << 20
+
+	defaultUpstreamRetries        = 2
+	defaultRetryBackoffMillis     = 250
+	defaultBreakerThreshold       = 5
+	defaultBreakerCooldownSeconds = 30
+
+	defaultQuotaPollSeconds    = 120
+	defaultQuotaHistorySeconds = 3600
+
+	defaultAlertCheckSeconds    = 60
+	defaultAlertCooldownSeconds = 3600
+
+	defaultHeartbeatSeconds   = 15
+	defaultIdleTimeoutSeconds = 300
+)
+
+// Default returns the configuration used when config.json is empty.
+func Default() *Config {
+	cfg := &Config{}
+	cfg.applyDefaults()
+	return cfg
+}
+
+// Load reads config.json, treating a missing or empty file as defaults.
+func Load(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return Default(), nil
+		}
+		return nil, err
+	}
+
+	if len(bytes.TrimSpace(data)) == 0 {
+		return Default(), nil
+	}
+
+	var cfg Config
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("invalid config %s: %w", path, err)
+	}
+	cfg.applyDefaults()
+	return &cfg, nil
+}
+
+func (c *Config) applyDefaults() {
+	if c.Compaction.Mode == "" {
+		c.Compaction.Mode = CompactionTruncate
+	}
+	if c.Compaction.SummaryModel == "" {
+		c.Compaction.SummaryModel = defaultSummaryModel
+	}
+	if c.Compaction.KeepRecent <= 0 {
+		c.Compaction.KeepRecent = defaultKeepRecent
+	}
+	if c.StructuredOutputs.Retries == nil || *c.StructuredOutputs.Retries < 0 {
+		retries := defaultStructuredOutputRetries
+		c.StructuredOutputs.Retries = &retries
+	}
+	if c.Upstream.Retries == nil || *c.Upstream.Retries < 0 {
+		retries := defaultUpstreamRetries
+		c.Upstream.Retries = &retries
+	}
+	if c.Upstream.RetryBackoffMillis <= 0 {
+		c.Upstream.RetryBackoffMillis = defaultRetryBackoffMillis
+	}
+	if c.Upstream.BreakerThreshold == nil || *c.Upstream.BreakerThreshold < 0 {
+		threshold := defaultBreakerThreshold
+		c.Upstream.BreakerThreshold = &threshold
+	}
+	if c.Upstream.BreakerCooldownSeconds <= 0 {
+		c.Upstream.BreakerCooldownSeconds = defaultBreakerCooldownSeconds
+	}
+	if c.Quota.PollSeconds == nil || *c.Quota.PollSeconds < 0 {
+		poll := defaultQuotaPollSeconds
+		c.Quota.PollSeconds = &poll
+	}
+	if c.Quota.HistorySeconds == nil || *c.Quota.HistorySeconds < 0 {
+		history := defaultQuotaHistorySeconds
+		c.Quota.HistorySeconds = &history
+	}
+	if c.Streams.HeartbeatSeconds == nil || *c.Streams.HeartbeatSeconds < 0 {
+		heartbeat := defaultHeartbeatSeconds
+		c.Streams.HeartbeatSeconds = &heartbeat
+	}
+	if c.Streams.IdleTimeoutSeconds == nil || *c.Streams.IdleTimeoutSeconds < 0 {
+		idle := defaultIdleTimeoutSeconds
+		c.Streams.IdleTimeoutSeconds = &idle
+	}
+	if c.Alerts.CheckSeconds <= 0 {
+		c.Alerts.CheckSeconds = defaultAlertCheckSeconds
+	}
+	if c.Alerts.CooldownSeconds == nil || *c.Alerts.CooldownSeconds < 0 {
+		cooldown := defaultAlertCooldownSeconds
+		c.Alerts.CooldownSeconds = &cooldown
+	}
+	if c.ResponseStore.TTLHours <= 0 {
+		c.ResponseStore.TTLHours = defaultResponseStoreTTLHours
+	}
+	if c.ResponseStore.MaxEntries <= 0 {
+		c.ResponseStore.MaxEntries = defaultResponseStoreMaxEntries
+	}
+	if c.ResponseStore.MaxBytes <= 0 {
+		c.ResponseStore.MaxBytes = defaultResponseStoreMaxBytes
+	}
+}
+
+// FindAPIKey returns the configured key matching value, if any.
+func (c *Config) FindAPIKey(value string) (*APIKey, bool) {
+	for i := range c.APIKeys {
+		if c.APIKeys[i].Key != "" && c.APIKeys[i].Key == value {
+			return &c.APIKeys[i], true
+		}
+	}
+	return nil, false
+}
@@ -0,0 +1,240 @@
+package errors
+
+import (
+	"context"
+	"encoding/json"
+	stdErrors "errors"
+	"net/http"
+	"strings"
+)
+
+// Kind groups upstream failures by what a client can do about them.
+type Kind string
+
+const (
+	KindInvalidRequest  Kind = "invalid_request"
+	KindContextLength   Kind = "context_length"
+	KindAuthentication  Kind = "authentication"
+	KindPermission      Kind = "permission"
+	KindNotFound        Kind = "not_found"
+	KindRequestTooLarge Kind = "request_too_large"
+	KindRateLimit       Kind = "rate_limit"
+	KindQuota           Kind = "quota"
+	KindOverloaded      Kind = "overloaded"
+	KindTimeout         Kind = "timeout"
+	KindAPI             Kind = "api"
+)
+
+// Classified is an error reduced to the parts every API dialect renders.
+type Classified struct {
+	Kind    Kind
+	Status  int
+	Message string
+	// Code and Param are carried over from the upstream body when present.
+	Code       string
+	Param      string
+	RetryAfter string
+}
+
+// StreamError is an error reported in-band by an upstream event stream.
+type StreamError struct {
+	Message string
+	Type    string
+	Code    string
+}
+
+func (e *StreamError) Error() string {
+	return "upstream stream failed: " + e.Message
+}
+
+// ParseStreamError returns the error carried by an SSE data payload, or nil
+// when the payload is not an error. Both the OpenAI `{"error":{...}}` frame
+// and the Responses `{"type":"error",...}` event are recognized.
+func ParseStreamError(data []byte) *StreamError {
+	var frame struct {
+		Type    string          `json:"type"`
+		Message string          `json:"message"`
+		Code    json.RawMessage `json:"code"`
+		Error   json.RawMessage `json:"error"`
+	}
+	if json.Unmarshal(data, &frame) != nil {
+		return nil
+	}
+	if len(frame.Error) > 0 && string(frame.Error) != "null" {
+		body := parseBody(data)
+		return &StreamError{Message: body.message, Type: body.errorType, Code: body.code}
+	}
+	if frame.Type == "error" {
+		return &StreamError{Message: frame.Message, Type: frame.Type, Code: rawString(frame.Code)}
+	}
+	return nil
+}
+
+// Classify inspects an upstream HTTP error, in-band stream error or local
+// failure and decides what kind of error it is.
+func Classify(err error) Classified {
+	var httpErr *HTTPError
+	if stdErrors.As(err, &httpErr) {
+		body := parseBody(httpErr.Body)
+		c := Classified{
+			Status:  httpErr.Response.StatusCode,
+			Message: body.message,
+			Code:    body.code,
+			Param:   body.param,
+		}
+		if c.Message == "" {
+			c.Message = httpErr.Message
+		}
+		if httpErr.Response.Header != nil {
+			c.RetryAfter = httpErr.Response.Header.Get("Retry-After")
+		}
+		c.Kind = classify(c.Status, body.errorType, c.Code, c.Message)
+		return c
+	}
+
+	var streamErr *StreamError
+	if stdErrors.As(err, &streamErr) {
+		c := Classified{Message: streamErr.Message, Code: streamErr.Code}
+		c.Kind = classify(0, streamErr.Type, c.Code, c.Message)
+		c.Status = c.Kind.Status()
+		return c
+	}
+
+	c := Classified{Kind: KindAPI, Message: err.Error()}
+	if stdErrors.Is(err, context.DeadlineExceeded) {
+		c.Kind = KindTimeout
+	}
+	c.Status = c.Kind.Status()
+	return c
+}
+
+// Status is the HTTP status conventionally used for the kind.
+func (k Kind) Status() int {
+	switch k {
+	case KindInvalidRequest, KindContextLength:
+		return http.StatusBadRequest
+	case KindAuthentication:
+		return http.StatusUnauthorized
+	case KindPermission:
+		return http.StatusForbidden
+	case KindNotFound:
+		return http.StatusNotFound
+	case KindRequestTooLarge:
+		return http.StatusRequestEntityTooLarge
+	case KindRateLimit, KindQuota:
+		return http.StatusTooManyRequests
+	case KindOverloaded:
+		return http.StatusServiceUnavailable
+	case KindTimeout:
+		return http.StatusGatewayTimeout
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+func classify(status int, errorType, code, message string) Kind {
+	text := strings.ToLower(errorType + " " + code + " " + message)
+	switch {
+	case containsAny(text, "context_length", "context length", "max_prompt_tokens", "prompt token count", "prompt is too long", "too many tokens", "maximum context"):
+		return KindContextLength
+	case status == http.StatusPaymentRequired || containsAny(text, "quota", "billing", "premium request", "monthly limit"):
+		return KindQuota
+	case containsAny(text, "rate_limit", "rate limit"):
+		return KindRateLimit
+	case (status == 0 || status >= 500) && containsAny(text, "overloaded"):
+		return KindOverloaded
+	case (status == 0 || status >= 500) && containsAny(text, "timeout", "timed out"):
+		return KindTimeout
+	}
+
+	switch {
+	case status == http.StatusUnauthorized:
+		return KindAuthentication
+	case status == http.StatusForbidden:
+		return KindPermission
+	case status == http.StatusNotFound:
+		return KindNotFound
+	case status == http.StatusRequestEntityTooLarge:
+		return KindRequestTooLarge
+	case status == http.StatusTooManyRequests:
+		return KindRateLimit
+	case status == http.StatusServiceUnavailable || status == 529:
+		return KindOverloaded
+	case status == http.StatusGatewayTimeout || status == http.StatusRequestTimeout:
+		return KindTimeout
+	case status >= 400 && status < 500:
+		return KindInvalidRequest
+	}
+	return KindAPI
+}
+
+type errorBody struct {
+	message   string
+	errorType string
+	code      string
+	param     string
+}
+
+// parseBody extracts error details from the shapes upstreams use: the
+// OpenAI and Anthropic envelopes, a bare string error, a top-level message,
+// or plain text.
+func parseBody(body []byte) errorBody {
+	var envelope struct {
+		Message string          `json:"message"`
+		Type    string          `json:"type"`
+		Code    json.RawMessage `json:"code"`
+		Error   json.RawMessage `json:"error"`
+	}
+	if json.Unmarshal(body, &envelope) != nil {
+		return errorBody{message: strings.TrimSpace(string(body))}
+	}
+
+	parsed := errorBody{message: envelope.Message, code: rawString(envelope.Code)}
+	if envelope.Type != "error" {
+		parsed.errorType = envelope.Type
+	}
+
+	var nested struct {
+		Message string          `json:"message"`
+		Type    string          `json:"type"`
+		Code    json.RawMessage `json:"code"`
+		Param   json.RawMessage `json:"param"`
+	}
+	if json.Unmarshal(envelope.Error, &nested) == nil {
+		if nested.Message != "" {
+			parsed.message = nested.Message
+		}
+		if nested.Type != "" {
+			parsed.errorType = nested.Type
+		}
+		if code := rawString(nested.Code); code != "" {
+			parsed.code = code
+		}
+		parsed.param = rawString(nested.Param)
+	} else if message := rawString(envelope.Error); message != "" {
+		parsed.message = message
+	}
+	return parsed
+}
+
+// rawString renders a JSON string or number as text; other values are empty.
+func rawString(raw json.RawMessage) string {
+	var s string
+	if json.Unmarshal(raw, &s) == nil {
+		return s
+	}
+	var n json.Number
+	if json.Unmarshal(raw, &n) == nil {
+		return n.String()
+	}
+	return ""
+}
+
+func containsAny(text string, needles ...string) bool {
+	for _, needle := range needles {
+		if strings.Contains(text, needle) {
+			return true
+		}
+	}
+	return false
+}
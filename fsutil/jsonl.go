@@ -0,0 +1,73 @@
+package fsutil
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"io"
+	"os"
+)
+
+// AppendJSONLine writes v as one line at the end of the JSON Lines file at
+// path. The line goes out in a single O_APPEND write, so readers never see
+// part of it once the write returns.
+func AppendJSONLine(path string, v any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o600)
+	if err != nil {
+		return err
+	}
+	if _, err := file.Write(append(data, '\n')); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// WriteJSONLines atomically replaces the file at path with one JSON line per
+// item.
+func WriteJSONLines[T any](path string, items []T) error {
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	for _, item := range items {
+		if err := encoder.Encode(item); err != nil {
+			return err
+		}
+	}
+	return WriteFileAtomic(path, buf.Bytes())
+}
+
+// ReadJSONLines decodes a JSON Lines file, oldest line first. Lines that do
+// not parse, such as one still being appended, are skipped. A missing file
+// has no lines.
+func ReadJSONLines[T any](path string) ([]T, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	defer file.Close()
+
+	var items []T
+	reader := bufio.NewReader(file)
+	for {
+		line, err := reader.ReadBytes('\n')
+		if len(bytes.TrimSpace(line)) > 0 {
+			var item T
+			if json.Unmarshal(line, &item) == nil {
+				items = append(items, item)
+			}
+		}
+		if err != nil {
+			if err == io.EOF {
+				return items, nil
+			}
+			return items, err
+		}
+	}
+}
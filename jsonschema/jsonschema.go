@@ -0,0 +1,486 @@
+// Package jsonschema implements the subset of JSON Schema used by OpenAI
+// structured outputs: types, objects, arrays, enums, combinators and local
+// $ref pointers. Unknown keywords such as "format" are ignored.
+package jsonschema
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/url"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// Schema is a compiled JSON Schema document.
+type Schema struct {
+	root     any
+	patterns map[string]*regexp.Regexp
+}
+
+// ValidationError describes the first place a value departs from the schema.
+type ValidationError struct {
+	Path    string
+	Message string
+}
+
+func (e *ValidationError) Error() string {
+	if e.Path == "" {
+		return e.Message
+	}
+	return e.Path + ": " + e.Message
+}
+
+// Compile parses a schema document. Only objects and booleans are valid roots.
+func Compile(raw json.RawMessage) (*Schema, error) {
+	var root any
+	if err := json.Unmarshal(raw, &root); err != nil {
+		return nil, fmt.Errorf("invalid schema: %w", err)
+	}
+	switch root.(type) {
+	case map[string]any, bool:
+	default:
+		return nil, fmt.Errorf("invalid schema: must be an object or boolean")
+	}
+	return &Schema{root: root, patterns: make(map[string]*regexp.Regexp)}, nil
+}
+
+// ValidateJSON decodes data and validates the result.
+func (s *Schema) ValidateJSON(data []byte) error {
+	var value any
+	if err := json.Unmarshal(data, &value); err != nil {
+		return &ValidationError{Message: "not valid JSON: " + err.Error()}
+	}
+	return s.Validate(value)
+}
+
+// Validate checks a value decoded by encoding/json against the schema.
+func (s *Schema) Validate(value any) error {
+	return s.validate(s.root, value, "$", make(refVisits))
+}
+
+// refVisit is a $ref being followed for the value at path.
+type refVisit struct {
+	ref  string
+	path string
+}
+
+// refVisits holds the $refs being followed. Reaching one again for the same
+// value means the references loop without consuming any of the instance.
+type refVisits map[refVisit]bool
+
+func (s *Schema) validate(schema any, value any, path string, refs refVisits) error {
+	switch node := schema.(type) {
+	case bool:
+		if !node {
+			return &ValidationError{Path: path, Message: "no value is allowed here"}
+		}
+		return nil
+	case map[string]any:
+		return s.validateObjectSchema(node, value, path, refs)
+	default:
+		return nil
+	}
+}
+
+func (s *Schema) validateObjectSchema(node map[string]any, value any, path string, refs refVisits) error {
+	if ref, ok := node["$ref"].(string); ok {
+		visit := refVisit{ref: ref, path: path}
+		if refs[visit] {
+			return &ValidationError{Path: path, Message: fmt.Sprintf("circular $ref %q", ref)}
+		}
+		target, err := s.resolve(ref)
+		if err != nil {
+			return &ValidationError{Path: path, Message: err.Error()}
+		}
+		refs[visit] = true
+		err = s.validate(target, value, path, refs)
+		delete(refs, visit)
+		if err != nil {
+			return err
+		}
+	}
+
+	if types, ok := node["type"]; ok {
+		if err := checkType(types, value, path); err != nil {
+			return err
+		}
+	}
+
+	if allowed, ok := node["enum"].([]any); ok {
+		found := false
+		for _, candidate := range allowed {
+			if equal(candidate, value) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return &ValidationError{Path: path, Message: "value is not one of the allowed enum values"}
+		}
+	}
+
+	if constant, ok := node["const"]; ok && !equal(constant, value) {
+		return &ValidationError{Path: path, Message: "value does not match const"}
+	}
+
+	switch v := value.(type) {
+	case string:
+		if err := s.validateString(node, v, path); err != nil {
+			return err
+		}
+	case float64:
+		if err := validateNumber(node, v, path); err != nil {
+			return err
+		}
+	case []any:
+		if err := s.validateArray(node, v, path, refs); err != nil {
+			return err
+		}
+	case map[string]any:
+		if err := s.validateObject(node, v, path, refs); err != nil {
+			return err
+		}
+	}
+
+	return s.validateCombinators(node, value, path, refs)
+}
+
+func (s *Schema) validateCombinators(node map[string]any, value any, path string, refs refVisits) error {
+	if subschemas, ok := node["allOf"].([]any); ok {
+		for _, sub := range subschemas {
+			if err := s.validate(sub, value, path, refs); err != nil {
+				return err
+			}
+		}
+	}
+
+	if subschemas, ok := node["anyOf"].([]any); ok {
+		var first error
+		matched := false
+		for _, sub := range subschemas {
+			err := s.validate(sub, value, path, refs)
+			if err == nil {
+				matched = true
+				break
+			}
+			if first == nil {
+				first = err
+			}
+		}
+		if !matched {
+			return &ValidationError{Path: path, Message: "value does not match any schema in anyOf: " + errorMessage(first)}
+		}
+	}
+
+	if subschemas, ok := node["oneOf"].([]any); ok {
+		matches := 0
+		for _, sub := range subschemas {
+			if s.validate(sub, value, path, refs) == nil {
+				matches++
+			}
+		}
+		if matches != 1 {
+			return &ValidationError{Path: path, Message: fmt.Sprintf("value matches %d schemas in oneOf, expected exactly 1", matches)}
+		}
+	}
+
+	if sub, ok := node["not"]; ok {
+		if s.validate(sub, value, path, refs) == nil {
+			return &ValidationError{Path: path, Message: "value must not match the schema in not"}
+		}
+	}
+
+	if cond, ok := node["if"]; ok {
+		if s.validate(cond, value, path, refs) == nil {
+			if then, ok := node["then"]; ok {
+				return s.validate(then, value, path, refs)
+			}
+		} else if otherwise, ok := node["else"]; ok {
+			return s.validate(otherwise, value, path, refs)
+		}
+	}
+	return nil
+}
+
+func (s *Schema) validateString(node map[string]any, value string, path string) error {
+	length := utf8.RuneCountInString(value)
+	if min, ok := number(node["minLength"]); ok && float64(length) < min {
+		return &ValidationError{Path: path, Message: fmt.Sprintf("string is shorter than %v characters", min)}
+	}
+	if max, ok := number(node["maxLength"]); ok && float64(length) > max {
+		return &ValidationError{Path: path, Message: fmt.Sprintf("string is longer than %v characters", max)}
+	}
+	if pattern, ok := node["pattern"].(string); ok {
+		re, err := s.pattern(pattern)
+		if err != nil {
+			return &ValidationError{Path: path, Message: fmt.Sprintf("invalid pattern %q: %v", pattern, err)}
+		}
+		if !re.MatchString(value) {
+			return &ValidationError{Path: path, Message: fmt.Sprintf("string does not match pattern %q", pattern)}
+		}
+	}
+	return nil
+}
+
+func validateNumber(node map[string]any, value float64, path string) error {
+	if min, ok := number(node["minimum"]); ok {
+		if exclusive, _ := node["exclusiveMinimum"].(bool); exclusive && value <= min {
+			return &ValidationError{Path: path, Message: fmt.Sprintf("must be greater than %v", min)}
+		}
+		if value < min {
+			return &ValidationError{Path: path, Message: fmt.Sprintf("must be at least %v", min)}
+		}
+	}
+	if max, ok := number(node["maximum"]); ok {
+		if exclusive, _ := node["exclusiveMaximum"].(bool); exclusive && value >= max {
+			return &ValidationError{Path: path, Message: fmt.Sprintf("must be less than %v", max)}
+		}
+		if value > max {
+			return &ValidationError{Path: path, Message: fmt.Sprintf("must be at most %v", max)}
+		}
+	}
+	if min, ok := number(node["exclusiveMinimum"]); ok && value <= min {
+		return &ValidationError{Path: path, Message: fmt.Sprintf("must be greater than %v", min)}
+	}
+	if max, ok := number(node["exclusiveMaximum"]); ok && value >= max {
+		return &ValidationError{Path: path, Message: fmt.Sprintf("must be less than %v", max)}
+	}
+	if divisor, ok := number(node["multipleOf"]); ok && divisor > 0 {
+		quotient := value / divisor
+		if math.Abs(quotient-math.Round(quotient)) > 1e-9 {
+			return &ValidationError{Path: path, Message: fmt.Sprintf("must be a multiple of %v", divisor)}
+		}
+	}
+	return nil
+}
+
+func (s *Schema) validateArray(node map[string]any, value []any, path string, refs refVisits) error {
+	if min, ok := number(node["minItems"]); ok && float64(len(value)) < min {
+		return &ValidationError{Path: path, Message: fmt.Sprintf("array has fewer than %v items", min)}
+	}
+	if max, ok := number(node["maxItems"]); ok && float64(len(value)) > max {
+		return &ValidationError{Path: path, Message: fmt.Sprintf("array has more than %v items", max)}
+	}
+	if unique, _ := node["uniqueItems"].(bool); unique {
+		for i := range value {
+			for j := i + 1; j < len(value); j++ {
+				if equal(value[i], value[j]) {
+					return &ValidationError{Path: path, Message: fmt.Sprintf("items %d and %d are equal", i, j)}
+				}
+			}
+		}
+	}
+
+	// prefixItems (2020-12) and array-form items (draft 7) validate positions;
+	// the remaining items are checked against items or additionalItems.
+	prefix, _ := node["prefixItems"].([]any)
+	rest, hasRest := node["items"]
+	if tuple, ok := rest.([]any); ok {
+		prefix = tuple
+		rest, hasRest = node["additionalItems"]
+	}
+
+	for i, item := range value {
+		itemPath := path + "[" + strconv.Itoa(i) + "]"
+		if i < len(prefix) {
+			if err := s.validate(prefix[i], item, itemPath, refs); err != nil {
+				return err
+			}
+			continue
+		}
+		if hasRest {
+			if err := s.validate(rest, item, itemPath, refs); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (s *Schema) validateObject(node map[string]any, value map[string]any, path string, refs refVisits) error {
+	if required, ok := node["required"].([]any); ok {
+		for _, name := range required {
+			key, _ := name.(string)
+			if _, present := value[key]; !present {
+				return &ValidationError{Path: path, Message: fmt.Sprintf("missing required property %q", key)}
+			}
+		}
+	}
+	if min, ok := number(node["minProperties"]); ok && float64(len(value)) < min {
+		return &ValidationError{Path: path, Message: fmt.Sprintf("object has fewer than %v properties", min)}
+	}
+	if max, ok := number(node["maxProperties"]); ok && float64(len(value)) > max {
+		return &ValidationError{Path: path, Message: fmt.Sprintf("object has more than %v properties", max)}
+	}
+
+	properties, _ := node["properties"].(map[string]any)
+	patternProperties, _ := node["patternProperties"].(map[string]any)
+	additional, hasAdditional := node["additionalProperties"]
+
+	// Iterate in a stable order so the reported error is deterministic.
+	keys := make([]string, 0, len(value))
+	for key := range value {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		propPath := path + "." + key
+		matched := false
+		if sub, ok := properties[key]; ok {
+			matched = true
+			if err := s.validate(sub, value[key], propPath, refs); err != nil {
+				return err
+			}
+		}
+		for pattern, sub := range patternProperties {
+			re, err := s.pattern(pattern)
+			if err != nil || !re.MatchString(key) {
+				continue
+			}
+			matched = true
+			if err := s.validate(sub, value[key], propPath, refs); err != nil {
+				return err
+			}
+		}
+		if matched || !hasAdditional {
+			continue
+		}
+		if allowed, ok := additional.(bool); ok && !allowed {
+			return &ValidationError{Path: path, Message: fmt.Sprintf("unexpected property %q", key)}
+		}
+		if err := s.validate(additional, value[key], propPath, refs); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// resolve follows a local JSON pointer such as "#/$defs/item".
+func (s *Schema) resolve(ref string) (any, error) {
+	if ref == "#" {
+		return s.root, nil
+	}
+	if !strings.HasPrefix(ref, "#/") {
+		return nil, fmt.Errorf("unsupported $ref %q: only local references are allowed", ref)
+	}
+	current := s.root
+	for _, token := range strings.Split(ref[2:], "/") {
+		// The pointer is a URI fragment, so it is percent-decoded first.
+		if unescaped, err := url.PathUnescape(token); err == nil {
+			token = unescaped
+		}
+		token = strings.ReplaceAll(strings.ReplaceAll(token, "~1", "/"), "~0", "~")
+		object, ok := current.(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("unresolvable $ref %q", ref)
+		}
+		if current, ok = object[token]; !ok {
+			return nil, fmt.Errorf("unresolvable $ref %q", ref)
+		}
+	}
+	return current, nil
+}
+
+func (s *Schema) pattern(expr string) (*regexp.Regexp, error) {
+	if re, ok := s.patterns[expr]; ok {
+		return re, nil
+	}
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		return nil, err
+	}
+	s.patterns[expr] = re
+	return re, nil
+}
+
+func checkType(types any, value any, path string) error {
+	var names []string
+	switch t := types.(type) {
+	case string:
+		names = []string{t}
+	case []any:
+		for _, name := range t {
+			if str, ok := name.(string); ok {
+				names = append(names, str)
+			}
+		}
+	default:
+		return nil
+	}
+
+	for _, name := range names {
+		if hasType(name, value) {
+			return nil
+		}
+	}
+	return &ValidationError{Path: path, Message: fmt.Sprintf("expected %s, got %s", strings.Join(names, " or "), typeName(value))}
+}
+
+func hasType(name string, value any) bool {
+	switch name {
+	case "null":
+		return value == nil
+	case "boolean":
+		_, ok := value.(bool)
+		return ok
+	case "string":
+		_, ok := value.(string)
+		return ok
+	case "number":
+		_, ok := value.(float64)
+		return ok
+	case "integer":
+		n, ok := value.(float64)
+		return ok && n == math.Trunc(n)
+	case "array":
+		_, ok := value.([]any)
+		return ok
+	case "object":
+		_, ok := value.(map[string]any)
+		return ok
+	}
+	return false
+}
+
+func typeName(value any) string {
+	switch v := value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case string:
+		return "string"
+	case float64:
+		if v == math.Trunc(v) {
+			return "integer"
+		}
+		return "number"
+	case []any:
+		return "array"
+	case map[string]any:
+		return "object"
+	}
+	return fmt.Sprintf("%T", value)
+}
+
+func number(v any) (float64, bool) {
+	n, ok := v.(float64)
+	return n, ok
+}
+
+func equal(a, b any) bool {
+	return reflect.DeepEqual(a, b)
+}
+
+func errorMessage(err error) string {
+	if err == nil {
+		return ""
+	}
+	return err.Error()
+}
@@ -0,0 +1,99 @@
+package jsonschema
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// suiteGroup is one schema with its cases, in the layout of the
+// JSON-Schema-Test-Suite. The files in testdata are taken from the suite for
+// the keywords this package supports.
+type suiteGroup struct {
+	Description string          `json:"description"`
+	Schema      json.RawMessage `json:"schema"`
+	Tests       []struct {
+		Description string          `json:"description"`
+		Data        json.RawMessage `json:"data"`
+		Valid       bool            `json:"valid"`
+	} `json:"tests"`
+}
+
+func TestSuite(t *testing.T) {
+	files, err := filepath.Glob(filepath.Join("testdata", "*.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) == 0 {
+		t.Fatal("no test files in testdata")
+	}
+
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var groups []suiteGroup
+		if err := json.Unmarshal(data, &groups); err != nil {
+			t.Fatalf("%s: %v", file, err)
+		}
+
+		for _, group := range groups {
+			t.Run(strings.TrimSuffix(filepath.Base(file), ".json")+"/"+group.Description, func(t *testing.T) {
+				schema, err := Compile(group.Schema)
+				if err != nil {
+					t.Fatalf("Compile: %v", err)
+				}
+				for _, test := range group.Tests {
+					err := schema.ValidateJSON(test.Data)
+					if test.Valid && err != nil {
+						t.Errorf("%s: want valid, got %v", test.Description, err)
+					}
+					if !test.Valid && err == nil {
+						t.Errorf("%s: want invalid, got valid", test.Description)
+					}
+				}
+			})
+		}
+	}
+}
+
+func TestDeeplyNestedInstance(t *testing.T) {
+	schema, err := Compile(json.RawMessage(`{"type": "object", "properties": {"child": {"$ref": "#"}}, "additionalProperties": false}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	const depth = 500
+	value := strings.Repeat(`{"child": `, depth) + "{}" + strings.Repeat("}", depth)
+	if err := schema.ValidateJSON([]byte(value)); err != nil {
+		t.Fatalf("want valid, got %v", err)
+	}
+
+	invalid := strings.Repeat(`{"child": `, depth) + `{"other": 1}` + strings.Repeat("}", depth)
+	if err := schema.ValidateJSON([]byte(invalid)); err == nil {
+		t.Fatal("want invalid, got valid")
+	}
+}
+
+func TestCircularRef(t *testing.T) {
+	tests := []string{
+		`{"$ref": "#"}`,
+		`{"$defs": {"a": {"$ref": "#/$defs/b"}, "b": {"$ref": "#/$defs/a"}}, "$ref": "#/$defs/a"}`,
+		`{"$defs": {"a": {"allOf": [{"$ref": "#/$defs/a"}]}}, "properties": {"x": {"$ref": "#/$defs/a"}}}`,
+	}
+	for _, raw := range tests {
+		schema, err := Compile(json.RawMessage(raw))
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = schema.ValidateJSON([]byte(`{"x": 1}`))
+		var validationErr *ValidationError
+		if !errors.As(err, &validationErr) || !strings.Contains(validationErr.Message, "circular $ref") {
+			t.Errorf("%s: want circular $ref error, got %v", raw, err)
+		}
+	}
+}
@@ -2,6 +2,7 @@ package logger
 
 import (
 	"fmt"
+	"io"
 	"log"
 	"os"
 	"sync/atomic"
@@ -25,6 +26,12 @@ func init() {
 	log.SetFlags(0)
 }
 
+// SetOutput redirects log lines, e.g. to stderr when stdout carries
+// machine-readable output.
+func SetOutput(w io.Writer) {
+	log.SetOutput(w)
+}
+
 func SetLevel(level Level) {
 	currentLevel.Store(int32(level))
 }
@@ -7,6 +7,7 @@ import (
 	"os"
 	"os/signal"
 	"strconv"
+	"strings"
 	"syscall"
 
 	"internal/app"
@@ -31,7 +32,13 @@ func main() {
 	case "auth":
 		err = runAuth(ctx, args)
 	case "check-usage":
-		err = app.RunCheckUsage(ctx)
+		err = runCheckUsage(ctx, args)
+	case "report":
+		err = runReport(args)
+	case "usage-history":
+		err = runUsageHistory(args)
+	case "models":
+		err = runModels(ctx, args)
 	default:
 		usage()
 		os.Exit(1)
@@ -72,6 +79,8 @@ func runStart(ctx context.Context, args []string) error {
 
 	proxyEnv := fs.Bool("proxy-env", false, "Initialize proxy from environment variables")
 
+	batchWorkers := fs.Int("batch-workers", 4, "Maximum number of batch requests processed concurrently")
+
 	if err := fs.Parse(args); err != nil {
 		return err
 	}
@@ -99,6 +108,7 @@ func runStart(ctx context.Context, args []string) error {
 		GitHubToken:      *githubToken,
 		ShowToken:        *showToken,
 		ProxyEnv:         *proxyEnv,
+		BatchWorkers:     *batchWorkers,
 	})
 }
 
@@ -120,10 +130,114 @@ func runAuth(ctx context.Context, args []string) error {
 	})
 }
 
+func runCheckUsage(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("check-usage", flag.ExitOnError)
+
+	jsonOutput := fs.Bool("json", false, "Print the raw usage response as JSON (same as --format json)")
+	format := fs.String("format", "text", "Output format (text, table, csv, json)")
+	watch := fs.Duration("watch", 0, "Refresh at this interval, e.g. 30s (0 prints once)")
+
+	accountType := fs.String("account-type", "individual", "Account type to use (individual, business, enterprise)")
+	fs.StringVar(accountType, "a", "individual", "Account type to use")
+
+	githubToken := fs.String("github-token", "", "Provide GitHub token directly")
+	fs.StringVar(githubToken, "g", "", "Provide GitHub token directly")
+
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if *jsonOutput {
+		*format = "json"
+	}
+
+	return app.RunCheckUsage(ctx, app.RunCheckUsageOptions{
+		Format:      *format,
+		Watch:       *watch,
+		GitHubToken: *githubToken,
+		AccountType: *accountType,
+	})
+}
+
+func runReport(args []string) error {
+	fs := flag.NewFlagSet("report", flag.ExitOnError)
+
+	since := fs.String("since", "", "First day to include (YYYY-MM-DD)")
+	until := fs.String("until", "", "Last day to include (YYYY-MM-DD)")
+	groupBy := fs.String("group-by", "day,key,model", "Comma separated grouping: day, key, model")
+	format := fs.String("format", "table", "Output format (table, json)")
+
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	return app.RunReport(app.RunReportOptions{
+		Since:   *since,
+		Until:   *until,
+		GroupBy: *groupBy,
+		Format:  *format,
+	})
+}
+
+func runUsageHistory(args []string) error {
+	fs := flag.NewFlagSet("usage-history", flag.ExitOnError)
+
+	days := fs.Int("days", 30, "Number of days to chart")
+	quota := fs.String("quota", "", "Only show this quota (e.g. premium_interactions)")
+	jsonOutput := fs.Bool("json", false, "Print trends as JSON")
+
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	return app.RunUsageHistory(app.RunUsageHistoryOptions{
+		Days:  *days,
+		Quota: *quota,
+		JSON:  *jsonOutput,
+	})
+}
+
+func runModels(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("models", flag.ExitOnError)
+
+	jsonOutput := fs.Bool("json", false, "Print the models as JSON")
+	supports := fs.String("supports", "", "Only show models supporting these capabilities, comma separated (tools, parallel_tools, vision, streaming, structured_outputs, dimensions)")
+	endpoint := fs.String("endpoint", "", "Only show models supporting this endpoint (e.g. /responses)")
+
+	accountType := fs.String("account-type", "individual", "Account type to use (individual, business, enterprise)")
+	fs.StringVar(accountType, "a", "individual", "Account type to use")
+
+	githubToken := fs.String("github-token", "", "Provide GitHub token directly")
+	fs.StringVar(githubToken, "g", "", "Provide GitHub token directly")
+
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	var capabilities []string
+	for _, capability := range strings.Split(*supports, ",") {
+		if capability = strings.TrimSpace(capability); capability != "" {
+			capabilities = append(capabilities, capability)
+		}
+	}
+
+	return app.RunModels(ctx, app.RunModelsOptions{
+		JSON:        *jsonOutput,
+		GitHubToken: *githubToken,
+		AccountType: *accountType,
+		Supports:    capabilities,
+		Endpoint:    *endpoint,
+	})
+}
+
 func usage() {
-	fmt.Println("Usage: copilot-api <command> [options]\n")
+	fmt.Println("Usage: copilot-api <command> [options]")
+	fmt.Println()
 	fmt.Println("Commands:")
-	fmt.Println("  start         Start the Copilot API server")
-	fmt.Println("  auth          Run GitHub auth flow without running the server")
-	fmt.Println("  check-usage   Show current GitHub Copilot usage/quota information")
+	fmt.Println("  start           Start the Copilot API server")
+	fmt.Println("  auth            Run GitHub auth flow without running the server")
+	fmt.Println("  check-usage     Show current GitHub Copilot usage/quota information")
+	fmt.Println("  report          Summarize locally recorded requests by day, key and model")
+	fmt.Println("  usage-history   Show quota burn rate and projected exhaustion from snapshots")
+	fmt.Println("  models          List Copilot models and their capabilities")
 }
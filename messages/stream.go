@@ -42,14 +42,8 @@ func TranslateChunkToAnthropicEvents(chunk copilot.ChatCompletionChunk, state *A
 		event.Message.Model = chunk.Model
 		event.Message.StopReason = nil
 		event.Message.StopSequence = nil
-		usage := AnthropicUsage{
-			InputTokens:  chunkUsagePromptTokens(chunk),
-			OutputTokens: 0,
-		}
-		if cached := chunkUsageCachedPromptTokens(chunk); cached != nil {
-			value := *cached
-			usage.CacheReadInputTokens = &value
-		}
+		usage := chunkUsage(chunk)
+		usage.OutputTokens = 0
 		event.Message.Usage = usage
 
 		data, err := json.Marshal(event)
@@ -60,8 +54,57 @@ func TranslateChunkToAnthropicEvents(chunk copilot.ChatCompletionChunk, state *A
 		state.MessageStartSent = true
 	}
 
+	if delta.ReasoningText != nil && *delta.ReasoningText != "" {
+		if state.ContentBlockOpen && !state.ThinkingBlockOpen {
+			stopEvent := AnthropicContentBlockStopEvent{
+				Type:  "content_block_stop",
+				Index: state.ContentBlockIndex,
+			}
+			if data, err := json.Marshal(stopEvent); err == nil {
+				events = append(events, SSEEvent{Type: stopEvent.Type, Data: data})
+			} else {
+				return nil, err
+			}
+			state.ContentBlockIndex++
+			state.ContentBlockOpen = false
+		}
+
+		if !state.ContentBlockOpen {
+			startEvent := AnthropicContentBlockStartEvent{
+				Type:  "content_block_start",
+				Index: state.ContentBlockIndex,
+				ContentBlock: map[string]string{
+					"type":      "thinking",
+					"thinking":  "",
+					"signature": "",
+				},
+			}
+			if data, err := json.Marshal(startEvent); err == nil {
+				events = append(events, SSEEvent{Type: startEvent.Type, Data: data})
+			} else {
+				return nil, err
+			}
+			state.ContentBlockOpen = true
+			state.ThinkingBlockOpen = true
+		}
+
+		deltaEvent := AnthropicContentBlockDeltaEvent{
+			Type:  "content_block_delta",
+			Index: state.ContentBlockIndex,
+			Delta: map[string]string{
+				"type":     "thinking_delta",
+				"thinking": *delta.ReasoningText,
+			},
+		}
+		if data, err := json.Marshal(deltaEvent); err == nil {
+			events = append(events, SSEEvent{Type: deltaEvent.Type, Data: data})
+		} else {
+			return nil, err
+		}
+	}
+
 	if delta.Content != nil {
-		if state.ContentBlockOpen && isToolBlockOpen(state) {
+		if state.ContentBlockOpen && (isToolBlockOpen(state) || state.ThinkingBlockOpen) {
 			stopEvent := AnthropicContentBlockStopEvent{
 				Type:  "content_block_stop",
 				Index: state.ContentBlockIndex,
@@ -73,6 +116,7 @@ func TranslateChunkToAnthropicEvents(chunk copilot.ChatCompletionChunk, state *A
 			}
 			state.ContentBlockIndex++
 			state.ContentBlockOpen = false
+			state.ThinkingBlockOpen = false
 		}
 
 		if !state.ContentBlockOpen {
@@ -122,6 +166,7 @@ func TranslateChunkToAnthropicEvents(chunk copilot.ChatCompletionChunk, state *A
 					}
 					state.ContentBlockIndex++
 					state.ContentBlockOpen = false
+					state.ThinkingBlockOpen = false
 				}
 
 				state.ToolCalls[toolCall.Index] = anthropicToolCallState{
@@ -180,6 +225,7 @@ func TranslateChunkToAnthropicEvents(chunk copilot.ChatCompletionChunk, state *A
 				return nil, err
 			}
 			state.ContentBlockOpen = false
+			state.ThinkingBlockOpen = false
 		}
 
 		reason := *choice.FinishReason
@@ -191,14 +237,7 @@ func TranslateChunkToAnthropicEvents(chunk copilot.ChatCompletionChunk, state *A
 			messageDelta.Delta.StopReason = mapped
 		}
 		messageDelta.Delta.StopSequence = nil
-		usage := AnthropicUsage{
-			InputTokens:  chunkUsagePromptTokens(chunk),
-			OutputTokens: chunkUsageCompletionTokens(chunk),
-		}
-		if cached := chunkUsageCachedPromptTokens(chunk); cached != nil {
-			value := *cached
-			usage.CacheReadInputTokens = &value
-		}
+		usage := chunkUsage(chunk)
 		messageDelta.Usage = &usage
 
 		if data, err := json.Marshal(messageDelta); err == nil {
@@ -218,16 +257,6 @@ func TranslateChunkToAnthropicEvents(chunk copilot.ChatCompletionChunk, state *A
 	return events, nil
 }
 
-func TranslateStreamError() SSEEvent {
-	event := AnthropicErrorEvent{
-		Type: "error",
-	}
-	event.Error.Type = "api_error"
-	event.Error.Message = "An unexpected error occurred during streaming."
-	data, _ := json.Marshal(event)
-	return SSEEvent{Type: event.Type, Data: data}
-}
-
 func isToolBlockOpen(state *AnthropicStreamState) bool {
 	if !state.ContentBlockOpen {
 		return false
@@ -240,28 +269,9 @@ func isToolBlockOpen(state *AnthropicStreamState) bool {
 	return false
 }
 
-func chunkUsagePromptTokens(chunk copilot.ChatCompletionChunk) int {
-	if chunk.Usage == nil {
-		return 0
-	}
-	tokens := chunk.Usage.PromptTokens
-	if chunk.Usage.PromptTokensDetails != nil {
-		tokens -= chunk.Usage.PromptTokensDetails.CachedTokens
-	}
-	return tokens
-}
-
-func chunkUsageCachedPromptTokens(chunk copilot.ChatCompletionChunk) *int {
-	if chunk.Usage == nil || chunk.Usage.PromptTokensDetails == nil {
-		return nil
-	}
-	value := chunk.Usage.PromptTokensDetails.CachedTokens
-	return &value
-}
-
-func chunkUsageCompletionTokens(chunk copilot.ChatCompletionChunk) int {
+func chunkUsage(chunk copilot.ChatCompletionChunk) AnthropicUsage {
 	if chunk.Usage == nil {
-		return 0
+		return anthropicUsage(0, 0, nil)
 	}
-	return chunk.Usage.CompletionTokens
+	return anthropicUsage(chunk.Usage.PromptTokens, chunk.Usage.CompletionTokens, chunk.Usage.PromptTokensDetails)
 }
@@ -8,6 +8,7 @@ import (
 )
 
 func TranslateToAnthropic(response copilot.ChatCompletionResponse) (AnthropicResponse, error) {
+	var thinkingBlocks []AnthropicAssistantContentBlock
 	var textBlocks []AnthropicAssistantContentBlock
 	var toolBlocks []AnthropicAssistantContentBlock
 
@@ -17,6 +18,10 @@ func TranslateToAnthropic(response copilot.ChatCompletionResponse) (AnthropicRes
 	}
 
 	for _, choice := range response.Choices {
+		if choice.Message.ReasoningText != nil && *choice.Message.ReasoningText != "" {
+			thinking := *choice.Message.ReasoningText
+			thinkingBlocks = append(thinkingBlocks, AnthropicAssistantContentBlock{Type: "thinking", Thinking: &thinking, Signature: stringPtr("")})
+		}
 		textBlocks = append(textBlocks, getAnthropicTextBlocks(choice.Message.Content)...)
 		toolBlocks = append(toolBlocks, getAnthropicToolUseBlocks(choice.Message.ToolCalls)...)
 
@@ -29,15 +34,12 @@ func TranslateToAnthropic(response copilot.ChatCompletionResponse) (AnthropicRes
 		}
 	}
 
-	usage := AnthropicUsage{
-		InputTokens:  usagePromptTokens(response),
-		OutputTokens: usageCompletionTokens(response),
-	}
-	if cached := usageCachedPromptTokens(response); cached != nil {
-		usage.CacheReadInputTokens = cached
+	usage := anthropicUsage(0, 0, nil)
+	if response.Usage != nil {
+		usage = anthropicUsage(response.Usage.PromptTokens, response.Usage.CompletionTokens, response.Usage.PromptTokensDetails)
 	}
 
-	content := append(textBlocks, toolBlocks...)
+	content := append(append(thinkingBlocks, textBlocks...), toolBlocks...)
 	return AnthropicResponse{
 		ID:           response.ID,
 		Type:         "message",
@@ -103,29 +105,3 @@ func mapFinishReason(reason string) *string {
 	}
 	return mapOpenAIStopReasonToAnthropic(&reason)
 }
-
-func usagePromptTokens(resp copilot.ChatCompletionResponse) int {
-	if resp.Usage == nil {
-		return 0
-	}
-	tokens := resp.Usage.PromptTokens
-	if resp.Usage.PromptTokensDetails != nil {
-		tokens -= resp.Usage.PromptTokensDetails.CachedTokens
-	}
-	return tokens
-}
-
-func usageCompletionTokens(resp copilot.ChatCompletionResponse) int {
-	if resp.Usage == nil {
-		return 0
-	}
-	return resp.Usage.CompletionTokens
-}
-
-func usageCachedPromptTokens(resp copilot.ChatCompletionResponse) *int {
-	if resp.Usage == nil || resp.Usage.PromptTokensDetails == nil {
-		return nil
-	}
-	value := resp.Usage.PromptTokensDetails.CachedTokens
-	return &value
-}
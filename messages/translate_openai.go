@@ -47,8 +47,13 @@ func TranslateToOpenAI(payload AnthropicMessagesPayload) (copilot.ChatCompletion
 	tools := translateAnthropicTools(payload.Tools)
 	toolChoice := translateAnthropicToolChoice(payload.ToolChoice)
 
+	model := translateModelName(payload.Model)
+	if !supportsPromptCaching(model) {
+		stripCacheControl(chatMessages, tools)
+	}
+
 	return copilot.ChatCompletionsPayload{
-		Model:       translateModelName(payload.Model),
+		Model:       model,
 		Messages:    chatMessages,
 		MaxTokens:   maxTokens,
 		Stop:        stop,
@@ -71,6 +76,39 @@ func translateModelName(model string) string {
 	return model
 }
 
+// supportsPromptCaching reports whether Copilot accepts cache breakpoints for model.
+func supportsPromptCaching(model string) bool {
+	return strings.HasPrefix(model, "claude-")
+}
+
+func stripCacheControl(messages []copilot.Message, tools []copilot.Tool) {
+	for i := range messages {
+		messages[i].CopilotCacheControl = nil
+	}
+	for i := range tools {
+		tools[i].CopilotCacheControl = nil
+	}
+}
+
+func translateCacheControl(cache *AnthropicCacheControl) *copilot.CacheControl {
+	if cache == nil {
+		return nil
+	}
+	return &copilot.CacheControl{Type: cache.Type, TTL: cache.TTL}
+}
+
+// blocksCacheControl returns the breakpoint of the last marked block, if any.
+// Copilot only supports breakpoints per message, so they are hoisted there.
+func blocksCacheControl(blocks []AnthropicContentBlock) *copilot.CacheControl {
+	var cache *copilot.CacheControl
+	for _, block := range blocks {
+		if block.CacheControl != nil {
+			cache = translateCacheControl(block.CacheControl)
+		}
+	}
+	return cache
+}
+
 func translateSystemPrompt(system *AnthropicSystemPrompt) ([]copilot.Message, error) {
 	if system == nil {
 		return nil, nil
@@ -85,14 +123,19 @@ func translateSystemPrompt(system *AnthropicSystemPrompt) ([]copilot.Message, er
 
 	if len(system.Blocks) > 0 {
 		var texts []string
+		var cache *copilot.CacheControl
 		for _, block := range system.Blocks {
 			texts = append(texts, block.Text)
+			if block.CacheControl != nil {
+				cache = translateCacheControl(block.CacheControl)
+			}
 		}
 		joined := joinWithDoubleNewline(texts)
 		return []copilot.Message{
 			{
-				Role:    "system",
-				Content: copilot.MessageContent{StringValue: &joined},
+				Role:                "system",
+				Content:             copilot.MessageContent{StringValue: &joined},
+				CopilotCacheControl: cache,
 			},
 		}, nil
 	}
@@ -144,8 +187,9 @@ func handleUserMessage(message AnthropicMessage) ([]copilot.Message, error) {
 					return nil, err
 				}
 				result = append(result, copilot.Message{
-					Role:    "user",
-					Content: content,
+					Role:                "user",
+					Content:             content,
+					CopilotCacheControl: blocksCacheControl(pending),
 				})
 				pending = nil
 			}
@@ -155,9 +199,10 @@ func handleUserMessage(message AnthropicMessage) ([]copilot.Message, error) {
 			}
 			toolCallID := toolResult.ToolUseID
 			result = append(result, copilot.Message{
-				Role:       "tool",
-				ToolCallID: &toolCallID,
-				Content:    msgContent,
+				Role:                "tool",
+				ToolCallID:          &toolCallID,
+				Content:             msgContent,
+				CopilotCacheControl: translateCacheControl(block.CacheControl),
 			})
 			continue
 		}
@@ -170,8 +215,9 @@ func handleUserMessage(message AnthropicMessage) ([]copilot.Message, error) {
 			return nil, err
 		}
 		result = append(result, copilot.Message{
-			Role:    "user",
-			Content: content,
+			Role:                "user",
+			Content:             content,
+			CopilotCacheControl: blocksCacheControl(pending),
 		})
 	}
 
@@ -217,6 +263,8 @@ func handleAssistantMessage(message AnthropicMessage) ([]copilot.Message, error)
 		}
 	}
 
+	cache := blocksCacheControl(message.Content.Blocks)
+
 	var messages []copilot.Message
 	if len(toolUseBlocks) > 0 {
 		content := strings.Join(textParts, "\n\n")
@@ -225,8 +273,9 @@ func handleAssistantMessage(message AnthropicMessage) ([]copilot.Message, error)
 			msgContent = copilot.MessageContent{StringValue: &content}
 		}
 		msg := copilot.Message{
-			Role:    "assistant",
-			Content: msgContent,
+			Role:                "assistant",
+			Content:             msgContent,
+			CopilotCacheControl: cache,
 		}
 		for _, toolBlock := range toolUseBlocks {
 			arguments := "{}"
@@ -258,14 +307,16 @@ func handleAssistantMessage(message AnthropicMessage) ([]copilot.Message, error)
 			return nil, err
 		}
 		messages = append(messages, copilot.Message{
-			Role:    "assistant",
-			Content: content,
+			Role:                "assistant",
+			Content:             content,
+			CopilotCacheControl: cache,
 		})
 	} else {
 		joined := strings.Join(textParts, "\n\n")
 		messages = append(messages, copilot.Message{
-			Role:    "assistant",
-			Content: copilot.MessageContent{StringValue: &joined},
+			Role:                "assistant",
+			Content:             copilot.MessageContent{StringValue: &joined},
+			CopilotCacheControl: cache,
 		})
 	}
 
@@ -370,6 +421,7 @@ func translateAnthropicTools(tools []AnthropicTool) []copilot.Tool {
 				Description: tool.Description,
 				Parameters:  tool.InputSchema,
 			},
+			CopilotCacheControl: translateCacheControl(tool.CacheControl),
 		})
 	}
 	return result
@@ -109,24 +109,25 @@ func (c AnthropicMessageContent) MarshalJSON() ([]byte, error) {
 }
 
 type AnthropicContentBlock struct {
-	Type      string
-	Text      *string
-	Source    *AnthropicImageSource
-	ToolUseID *string
-	Content   interface{}
-	IsError   *bool
-	ID        *string
-	Name      *string
-	Input     map[string]interface{}
-	Thinking  *string
-	Signature *string
+	Type         string
+	Text         *string
+	Source       *AnthropicImageSource
+	ToolUseID    *string
+	Content      interface{}
+	IsError      *bool
+	ID           *string
+	Name         *string
+	Input        map[string]interface{}
+	Thinking     *string
+	Signature    *string
+	CacheControl *AnthropicCacheControl
 }
 
 func (b AnthropicContentBlock) AsText() (*AnthropicTextBlock, bool) {
 	if b.Type != "text" || b.Text == nil {
 		return nil, false
 	}
-	return &AnthropicTextBlock{Type: "text", Text: *b.Text}, true
+	return &AnthropicTextBlock{Type: "text", Text: *b.Text, CacheControl: b.CacheControl}, true
 }
 
 func (b AnthropicContentBlock) AsThinking() (*AnthropicThinkingBlock, bool) {
@@ -187,6 +188,14 @@ func (b *AnthropicContentBlock) UnmarshalJSON(data []byte) error {
 		}
 	}
 
+	if cacheRaw, ok := raw["cache_control"]; ok && string(cacheRaw) != "null" {
+		var cache AnthropicCacheControl
+		if err := json.Unmarshal(cacheRaw, &cache); err != nil {
+			return err
+		}
+		b.CacheControl = &cache
+	}
+
 	switch b.Type {
 	case "text":
 		var text string
@@ -266,36 +275,45 @@ func (b *AnthropicContentBlock) UnmarshalJSON(data []byte) error {
 
 func (b AnthropicContentBlock) MarshalJSON() ([]byte, error) {
 	type alias struct {
-		Type      string                 `json:"type"`
-		Text      *string                `json:"text,omitempty"`
-		Source    *AnthropicImageSource  `json:"source,omitempty"`
-		ToolUseID *string                `json:"tool_use_id,omitempty"`
-		Content   interface{}            `json:"content,omitempty"`
-		IsError   *bool                  `json:"is_error,omitempty"`
-		ID        *string                `json:"id,omitempty"`
-		Name      *string                `json:"name,omitempty"`
-		Input     map[string]interface{} `json:"input,omitempty"`
-		Thinking  *string                `json:"thinking,omitempty"`
-		Signature *string                `json:"signature,omitempty"`
+		Type         string                 `json:"type"`
+		Text         *string                `json:"text,omitempty"`
+		Source       *AnthropicImageSource  `json:"source,omitempty"`
+		ToolUseID    *string                `json:"tool_use_id,omitempty"`
+		Content      interface{}            `json:"content,omitempty"`
+		IsError      *bool                  `json:"is_error,omitempty"`
+		ID           *string                `json:"id,omitempty"`
+		Name         *string                `json:"name,omitempty"`
+		Input        map[string]interface{} `json:"input,omitempty"`
+		Thinking     *string                `json:"thinking,omitempty"`
+		Signature    *string                `json:"signature,omitempty"`
+		CacheControl *AnthropicCacheControl `json:"cache_control,omitempty"`
 	}
 	return json.Marshal(alias{
-		Type:      b.Type,
-		Text:      b.Text,
-		Source:    b.Source,
-		ToolUseID: b.ToolUseID,
-		Content:   b.Content,
-		IsError:   b.IsError,
-		ID:        b.ID,
-		Name:      b.Name,
-		Input:     b.Input,
-		Thinking:  b.Thinking,
-		Signature: b.Signature,
+		Type:         b.Type,
+		Text:         b.Text,
+		Source:       b.Source,
+		ToolUseID:    b.ToolUseID,
+		Content:      b.Content,
+		IsError:      b.IsError,
+		ID:           b.ID,
+		Name:         b.Name,
+		Input:        b.Input,
+		Thinking:     b.Thinking,
+		Signature:    b.Signature,
+		CacheControl: b.CacheControl,
 	})
 }
 
 type AnthropicTextBlock struct {
-	Type string `json:"type"`
-	Text string `json:"text"`
+	Type         string                 `json:"type"`
+	Text         string                 `json:"text"`
+	CacheControl *AnthropicCacheControl `json:"cache_control,omitempty"`
+}
+
+// AnthropicCacheControl marks a prompt-caching breakpoint.
+type AnthropicCacheControl struct {
+	Type string  `json:"type"`
+	TTL  *string `json:"ttl,omitempty"`
 }
 
 type AnthropicImageBlock struct {
@@ -330,9 +348,10 @@ type AnthropicThinkingBlock struct {
 }
 
 type AnthropicTool struct {
-	Name        string                 `json:"name"`
-	Description *string                `json:"description,omitempty"`
-	InputSchema map[string]interface{} `json:"input_schema"`
+	Name         string                 `json:"name"`
+	Description  *string                `json:"description,omitempty"`
+	InputSchema  map[string]interface{} `json:"input_schema"`
+	CacheControl *AnthropicCacheControl `json:"cache_control,omitempty"`
 }
 
 type AnthropicResponse struct {
@@ -412,18 +431,11 @@ type AnthropicMessageStopEvent struct {
 	Type string `json:"type"`
 }
 
-type AnthropicErrorEvent struct {
-	Type  string `json:"type"`
-	Error struct {
-		Type    string `json:"type"`
-		Message string `json:"message"`
-	} `json:"error"`
-}
-
 type AnthropicStreamState struct {
 	MessageStartSent  bool
 	ContentBlockIndex int
 	ContentBlockOpen  bool
+	ThinkingBlockOpen bool
 	ToolCalls         map[int]anthropicToolCallState
 }
 
@@ -3,6 +3,8 @@ package messages
 import (
 	"encoding/json"
 	"fmt"
+
+	"internal/services/copilot"
 )
 
 func mapOpenAIStopReasonToAnthropic(reason *string) *string {
@@ -21,6 +23,26 @@ func mapOpenAIStopReasonToAnthropic(reason *string) *string {
 	return nil
 }
 
+// anthropicUsage converts OpenAI-style usage, where prompt tokens include cache
+// reads and writes, into Anthropic usage, which reports them separately.
+func anthropicUsage(promptTokens, completionTokens int, details *copilot.PromptTokensDetails) AnthropicUsage {
+	cacheRead, cacheCreation := 0, 0
+	if details != nil {
+		cacheRead = details.CachedTokens
+		cacheCreation = details.CacheCreationInputTokens
+	}
+	input := promptTokens - cacheRead - cacheCreation
+	if input < 0 {
+		input = 0
+	}
+	return AnthropicUsage{
+		InputTokens:              input,
+		OutputTokens:             completionTokens,
+		CacheCreationInputTokens: &cacheCreation,
+		CacheReadInputTokens:     &cacheRead,
+	}
+}
+
 func boolPtr(b bool) *bool {
 	return &b
 }
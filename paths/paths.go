@@ -6,9 +6,17 @@ import (
 )
 
 type Paths struct {
-	AppDir      string
-	GitHubToken string
-	ConfigPath  string
+	AppDir            string
+	GitHubToken       string
+	ConfigPath        string
+	MessageBatchesDir string
+	FilesDir          string
+	BatchesDir        string
+	ResponsesDir      string
+	ConversationsDir  string
+	BudgetLedger      string
+	UsageLedger       string
+	UsageHistory      string
 }
 
 var Default Paths
@@ -20,9 +28,17 @@ func init() {
 	}
 	appDir := filepath.Join(home, ".local", "share", "copilot-api")
 	Default = Paths{
-		AppDir:      appDir,
-		GitHubToken: filepath.Join(appDir, "github_token"),
-		ConfigPath:  filepath.Join(appDir, "config.json"),
+		AppDir:            appDir,
+		GitHubToken:       filepath.Join(appDir, "github_token"),
+		ConfigPath:        filepath.Join(appDir, "config.json"),
+		MessageBatchesDir: filepath.Join(appDir, "message_batches"),
+		FilesDir:          filepath.Join(appDir, "files"),
+		BatchesDir:        filepath.Join(appDir, "batches"),
+		ResponsesDir:      filepath.Join(appDir, "responses"),
+		ConversationsDir:  filepath.Join(appDir, "conversations"),
+		BudgetLedger:      filepath.Join(appDir, "budget_ledger.json"),
+		UsageLedger:       filepath.Join(appDir, "usage.jsonl"),
+		UsageHistory:      filepath.Join(appDir, "quota_history.jsonl"),
 	}
 }
 
@@ -31,6 +47,11 @@ func EnsurePaths(p Paths) error {
 	if err := os.MkdirAll(p.AppDir, 0o755); err != nil {
 		return err
 	}
+	for _, dir := range []string{p.MessageBatchesDir, p.FilesDir, p.BatchesDir, p.ResponsesDir, p.ConversationsDir} {
+		if err := os.MkdirAll(dir, 0o700); err != nil {
+			return err
+		}
+	}
 	if err := ensureFile(p.GitHubToken); err != nil {
 		return err
 	}
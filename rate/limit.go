@@ -1,6 +1,7 @@
 package rate
 
 import (
+	"context"
 	"time"
 
 	appErr "internal/errors"
@@ -58,3 +59,41 @@ func CheckRateLimit(s *state.State) error {
 	})
 	return nil
 }
+
+// AwaitRateLimit blocks until the rate limit allows another request,
+// regardless of the RateLimitWait setting. It is used for background work.
+func AwaitRateLimit(ctx context.Context, s *state.State) error {
+	for {
+		wait := reserve(s)
+		if wait <= 0 {
+			return nil
+		}
+		logger.Debug("Rate limit reached, background request waiting %v", wait)
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(wait):
+		}
+	}
+}
+
+// reserve records a request if allowed and otherwise returns the remaining wait.
+func reserve(s *state.State) time.Duration {
+	var wait time.Duration
+	s.Update(func(st *state.State) {
+		if st.RateLimitSeconds == nil {
+			return
+		}
+		now := time.Now().UnixMilli()
+		if st.LastRequestUnixMs != nil {
+			elapsed := time.Duration(now-*st.LastRequestUnixMs) * time.Millisecond
+			limit := time.Duration(*st.RateLimitSeconds) * time.Second
+			if elapsed <= limit {
+				wait = limit - elapsed + time.Millisecond
+				return
+			}
+		}
+		st.LastRequestUnixMs = &now
+	})
+	return wait
+}
@@ -0,0 +1,421 @@
+package responses
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"internal/logger"
+	"internal/services/copilot"
+)
+
+// RequestFromChat converts a chat completions payload into a Responses
+// request for models that are only served through /responses. Members
+// without a typed field are carried over. When the client sets a reasoning
+// effort, summaries are requested so they can be surfaced as reasoning_text.
+func RequestFromChat(payload copilot.ChatCompletionsPayload) (Request, error) {
+	req := Request{
+		Model:             payload.Model,
+		Temperature:       payload.Temperature,
+		TopP:              payload.TopP,
+		MaxOutputTokens:   payload.MaxCompletionTokens,
+		ParallelToolCalls: payload.ParallelToolCalls,
+		Stream:            payload.Stream,
+		User:              payload.User,
+		Extras:            payload.Extras,
+	}
+	if payload.ReasoningEffort != nil {
+		summary := "auto"
+		req.Reasoning = &Reasoning{Effort: payload.ReasoningEffort, Summary: &summary}
+	}
+	if req.MaxOutputTokens == nil {
+		req.MaxOutputTokens = payload.MaxTokens
+	}
+
+	var instructions []string
+	var items []InputItem
+	for _, message := range payload.Messages {
+		switch message.Role {
+		case "system", "developer":
+			// Leading system messages become instructions; later ones stay
+			// in place as developer messages.
+			if len(items) == 0 {
+				instructions = append(instructions, message.Content.Text())
+				continue
+			}
+			item, err := messageItem("developer", "input_text", message.Content)
+			if err != nil {
+				return req, err
+			}
+			items = append(items, item)
+
+		case "user":
+			item, err := messageItem("user", "input_text", message.Content)
+			if err != nil {
+				return req, err
+			}
+			items = append(items, item)
+
+		case "assistant":
+			if text := message.Content.Text(); text != "" {
+				item, err := messageItem("assistant", "output_text", message.Content)
+				if err != nil {
+					return req, err
+				}
+				items = append(items, item)
+			}
+			for _, call := range message.ToolCalls {
+				arguments := call.Function.Arguments
+				if arguments == "" {
+					arguments = "{}"
+				}
+				items = append(items, InputItem{
+					Type:      "function_call",
+					CallID:    call.ID,
+					Name:      call.Function.Name,
+					Arguments: arguments,
+				})
+			}
+
+		case "tool":
+			output, _ := json.Marshal(message.Content.Text())
+			item := InputItem{Type: "function_call_output", Output: output}
+			if message.ToolCallID != nil {
+				item.CallID = *message.ToolCallID
+			}
+			items = append(items, item)
+
+		default:
+			return req, fmt.Errorf("unsupported message role %q", message.Role)
+		}
+	}
+
+	if len(instructions) > 0 {
+		joined := strings.Join(instructions, "\n\n")
+		req.Instructions = &joined
+	}
+	input, err := json.Marshal(items)
+	if err != nil {
+		return req, err
+	}
+	req.Input = input
+
+	for _, tool := range payload.Tools {
+		parameters, err := json.Marshal(tool.Function.Parameters)
+		if err != nil {
+			return req, err
+		}
+		req.Tools = append(req.Tools, Tool{
+			Type:        "function",
+			Name:        tool.Function.Name,
+			Description: tool.Function.Description,
+			Parameters:  parameters,
+		})
+	}
+
+	if payload.ToolChoice != nil {
+		switch {
+		case payload.ToolChoice.StringValue != nil:
+			req.ToolChoice, _ = json.Marshal(*payload.ToolChoice.StringValue)
+		case payload.ToolChoice.ObjectValue != nil:
+			req.ToolChoice, _ = json.Marshal(map[string]string{
+				"type": "function",
+				"name": payload.ToolChoice.ObjectValue.Function.Name,
+			})
+		}
+	}
+
+	if format := payload.ResponseFormat; format != nil {
+		switch {
+		case format.Type == "json_object":
+			req.Text = &TextConfig{Format: &TextFormat{Type: "json_object"}}
+		case format.Type == "json_schema" && format.JSONSchema != nil:
+			req.Text = &TextConfig{Format: &TextFormat{
+				Type:        "json_schema",
+				Name:        format.JSONSchema.Name,
+				Description: format.JSONSchema.Description,
+				Schema:      format.JSONSchema.Schema,
+				Strict:      format.JSONSchema.Strict,
+			}}
+		}
+	}
+
+	return req, nil
+}
+
+func messageItem(role, textType string, content copilot.MessageContent) (InputItem, error) {
+	var parts []InputContent
+	if content.StringValue != nil {
+		parts = append(parts, InputContent{Type: textType, Text: *content.StringValue})
+	}
+	for _, part := range content.Parts {
+		switch {
+		case part.Type == "text" && part.Text != nil:
+			parts = append(parts, InputContent{Type: textType, Text: *part.Text})
+		case part.Type == "image_url" && part.ImageURL != nil:
+			parts = append(parts, InputContent{Type: "input_image", ImageURL: part.ImageURL.URL, Detail: part.ImageURL.Detail})
+		default:
+			return InputItem{}, fmt.Errorf("%s content is not supported for responses models", part.Type)
+		}
+	}
+	if parts == nil {
+		parts = []InputContent{}
+	}
+	data, err := json.Marshal(parts)
+	if err != nil {
+		return InputItem{}, err
+	}
+	return InputItem{Type: "message", Role: role, Content: data}, nil
+}
+
+// CompletionFromResponse converts a response object into a chat completion.
+func CompletionFromResponse(response Response) copilot.ChatCompletionResponse {
+	var texts, reasoning []string
+	var calls []copilot.ToolCall
+	for _, item := range response.Output {
+		switch item.Type {
+		case "message":
+			for _, content := range item.Content {
+				if content.Type == "output_text" {
+					texts = append(texts, content.Text)
+				}
+			}
+		case "function_call":
+			calls = append(calls, copilot.ToolCall{
+				ID:   item.CallID,
+				Type: "function",
+				Function: copilot.ToolCallFunction{
+					Name:      item.Name,
+					Arguments: item.Arguments,
+				},
+			})
+		case "reasoning":
+			for _, summary := range item.Summary {
+				reasoning = append(reasoning, summary.Text)
+			}
+		}
+	}
+
+	message := copilot.ResponseMessage{Role: "assistant"}
+	if len(texts) > 0 {
+		text := strings.Join(texts, "")
+		message.Content = copilot.MessageContent{StringValue: &text}
+	}
+	message.ToolCalls = calls
+	if len(reasoning) > 0 {
+		text := strings.Join(reasoning, "\n\n")
+		message.ReasoningText = &text
+	}
+
+	completion := copilot.ChatCompletionResponse{
+		ID:      response.ID,
+		Object:  "chat.completion",
+		Created: response.CreatedAt,
+		Model:   response.Model,
+		Choices: []copilot.ChoiceNonStreaming{{
+			Index:        0,
+			Message:      message,
+			FinishReason: finishReason(response, len(calls) > 0),
+		}},
+	}
+	if response.Usage != nil {
+		completion.Usage = &copilot.ChatUsage{
+			PromptTokens:            response.Usage.InputTokens,
+			CompletionTokens:        response.Usage.OutputTokens,
+			TotalTokens:             response.Usage.TotalTokens,
+			PromptTokensDetails:     &copilot.PromptTokensDetails{CachedTokens: response.Usage.InputTokensDetails.CachedTokens},
+			CompletionTokensDetails: &copilot.CompletionTokensDetails{ReasoningTokens: response.Usage.OutputTokensDetails.ReasoningTokens},
+		}
+	}
+	return completion
+}
+
+func finishReason(response Response, toolCalls bool) string {
+	if response.IncompleteDetails != nil {
+		switch response.IncompleteDetails.Reason {
+		case "max_output_tokens":
+			return "length"
+		case "content_filter":
+			return "content_filter"
+		}
+	}
+	if toolCalls {
+		return "tool_calls"
+	}
+	return "stop"
+}
+
+// streamEvent is the union of the Responses stream events used below.
+type streamEvent struct {
+	Type         string      `json:"type"`
+	Response     *Response   `json:"response"`
+	Item         *OutputItem `json:"item"`
+	ItemID       string      `json:"item_id"`
+	Delta        string      `json:"delta"`
+	SummaryIndex int         `json:"summary_index"`
+	Code         string      `json:"code"`
+	Message      string      `json:"message"`
+}
+
+// ChunkState converts Responses stream events into chat completion chunks.
+type ChunkState struct {
+	id        string
+	model     string
+	created   int64
+	toolCalls map[string]int
+}
+
+func NewChunkState() *ChunkState {
+	return &ChunkState{toolCalls: make(map[string]int)}
+}
+
+// TranslateEvent returns the chunks for one event and whether the stream has
+// ended. Failed responses are reported as errors.
+func (s *ChunkState) TranslateEvent(data []byte) ([]copilot.ChatCompletionChunk, bool, error) {
+	var event streamEvent
+	if err := json.Unmarshal(data, &event); err != nil {
+		return nil, false, err
+	}
+
+	switch event.Type {
+	case "response.created":
+		if event.Response != nil {
+			s.id, s.model, s.created = event.Response.ID, event.Response.Model, event.Response.CreatedAt
+		}
+		role := "assistant"
+		return []copilot.ChatCompletionChunk{s.chunk(copilot.Delta{Role: &role}, nil)}, false, nil
+
+	case "response.output_text.delta":
+		delta := event.Delta
+		return []copilot.ChatCompletionChunk{s.chunk(copilot.Delta{Content: &delta}, nil)}, false, nil
+
+	case "response.reasoning_summary_part.added":
+		if event.SummaryIndex == 0 {
+			return nil, false, nil
+		}
+		separator := "\n\n"
+		return []copilot.ChatCompletionChunk{s.chunk(copilot.Delta{ReasoningText: &separator}, nil)}, false, nil
+
+	case "response.reasoning_summary_text.delta":
+		delta := event.Delta
+		return []copilot.ChatCompletionChunk{s.chunk(copilot.Delta{ReasoningText: &delta}, nil)}, false, nil
+
+	case "response.output_item.added":
+		if event.Item == nil || event.Item.Type != "function_call" {
+			return nil, false, nil
+		}
+		index := len(s.toolCalls)
+		s.toolCalls[event.Item.ID] = index
+		call := copilot.ToolCall{
+			Index: index,
+			ID:    event.Item.CallID,
+			Type:  "function",
+			Function: copilot.ToolCallFunction{
+				Name:      event.Item.Name,
+				Arguments: event.Item.Arguments,
+			},
+		}
+		return []copilot.ChatCompletionChunk{s.chunk(copilot.Delta{ToolCalls: []copilot.ToolCall{call}}, nil)}, false, nil
+
+	case "response.function_call_arguments.delta":
+		index, ok := s.toolCalls[event.ItemID]
+		if !ok {
+			return nil, false, nil
+		}
+		call := copilot.ToolCall{Index: index, Function: copilot.ToolCallFunction{Arguments: event.Delta}}
+		return []copilot.ChatCompletionChunk{s.chunk(copilot.Delta{ToolCalls: []copilot.ToolCall{call}}, nil)}, false, nil
+
+	case "response.completed", "response.incomplete":
+		if event.Response == nil {
+			return nil, true, nil
+		}
+		reason := finishReason(*event.Response, len(s.toolCalls) > 0)
+		chunk := s.chunk(copilot.Delta{}, &reason)
+		if usage := event.Response.Usage; usage != nil {
+			chunk.Usage = &copilot.UsageDetails{
+				PromptTokens:            usage.InputTokens,
+				CompletionTokens:        usage.OutputTokens,
+				TotalTokens:             usage.TotalTokens,
+				PromptTokensDetails:     &copilot.PromptTokensDetails{CachedTokens: usage.InputTokensDetails.CachedTokens},
+				CompletionTokensDetails: &copilot.CompletionTokensDetails{ReasoningTokens: usage.OutputTokensDetails.ReasoningTokens},
+			}
+		}
+		return []copilot.ChatCompletionChunk{chunk}, true, nil
+
+	case "response.failed":
+		message := "response failed"
+		if event.Response != nil && event.Response.Error != nil {
+			message = event.Response.Error.Message
+		}
+		return nil, true, fmt.Errorf("%s", message)
+
+	case "error":
+		return nil, true, fmt.Errorf("%s", event.Message)
+	}
+	return nil, false, nil
+}
+
+func (s *ChunkState) chunk(delta copilot.Delta, finishReason *string) copilot.ChatCompletionChunk {
+	return copilot.ChatCompletionChunk{
+		ID:      s.id,
+		Object:  "chat.completion.chunk",
+		Created: s.created,
+		Model:   s.model,
+		Choices: []copilot.Choice{{Index: 0, Delta: delta, FinishReason: finishReason}},
+	}
+}
+
+// ToChatStream adapts a Responses stream to chat completion SSE messages,
+// ending with [DONE]. An upstream failure is forwarded as an error payload.
+func ToChatStream(ctx context.Context, upstream <-chan copilot.SSEMessage) <-chan copilot.SSEMessage {
+	out := make(chan copilot.SSEMessage)
+	go func() {
+		defer close(out)
+		send := func(data string) bool {
+			select {
+			case out <- copilot.SSEMessage{Data: data}:
+				return true
+			case <-ctx.Done():
+				return false
+			}
+		}
+
+		state := NewChunkState()
+		for msg := range upstream {
+			if msg.Err != nil {
+				select {
+				case out <- copilot.SSEMessage{Err: msg.Err}:
+				case <-ctx.Done():
+				}
+				return
+			}
+			if msg.Data == "" || msg.Data == "[DONE]" {
+				continue
+			}
+			chunks, done, err := state.TranslateEvent([]byte(msg.Data))
+			if err != nil {
+				logger.Error("Responses stream failed: %v", err)
+				data, _ := json.Marshal(map[string]any{
+					"error": map[string]any{"message": err.Error(), "type": "api_error"},
+				})
+				send(string(data))
+				return
+			}
+			for _, chunk := range chunks {
+				data, err := json.Marshal(chunk)
+				if err != nil {
+					continue
+				}
+				if !send(string(data)) {
+					return
+				}
+			}
+			if done {
+				break
+			}
+		}
+		send("[DONE]")
+	}()
+	return out
+}
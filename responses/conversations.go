@@ -0,0 +1,317 @@
+package responses
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"internal/fsutil"
+	"internal/logger"
+	"internal/services/copilot"
+)
+
+var (
+	ErrConversationNotFound = errors.New("conversation not found")
+	ErrItemNotFound         = errors.New("conversation item not found")
+)
+
+// Conversation mirrors the OpenAI conversation object.
+type Conversation struct {
+	ID        string            `json:"id"`
+	Object    string            `json:"object"`
+	CreatedAt int64             `json:"created_at"`
+	Metadata  map[string]string `json:"metadata"`
+}
+
+type conversationRecord struct {
+	Conversation
+	// Owner is the name of the API key that created the conversation.
+	Owner string            `json:"owner,omitempty"`
+	Items []json.RawMessage `json:"items"`
+}
+
+// conversationEntry is what is kept in memory for each conversation.
+type conversationEntry struct {
+	conversation Conversation
+	owner        string
+}
+
+// Conversations keeps conversation threads in the app directory, one file per
+// conversation holding its items oldest first. A conversation is only
+// visible to the API key that created it.
+type Conversations struct {
+	dir string
+
+	mu            sync.Mutex
+	conversations map[string]conversationEntry
+}
+
+func NewConversations(dir string) (*Conversations, error) {
+	c := &Conversations{dir: dir, conversations: make(map[string]conversationEntry)}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return c, nil
+		}
+		return nil, err
+	}
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasSuffix(name, ".json") || strings.HasPrefix(name, ".") {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			return nil, err
+		}
+		var record conversationRecord
+		if err := json.Unmarshal(data, &record); err != nil || record.ID == "" {
+			logger.Warn("Skipping unreadable conversation %s", name)
+			continue
+		}
+		c.conversations[record.ID] = conversationEntry{conversation: record.Conversation, owner: record.Owner}
+	}
+	return c, nil
+}
+
+// Create starts a conversation with optional initial items, which must
+// already be normalized.
+func (c *Conversations) Create(owner string, metadata map[string]string, items []json.RawMessage) (Conversation, error) {
+	if metadata == nil {
+		metadata = map[string]string{}
+	}
+	conversation := Conversation{
+		ID:        NewID("conv_"),
+		Object:    "conversation",
+		CreatedAt: time.Now().Unix(),
+		Metadata:  metadata,
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := c.writeLocked(conversationRecord{Conversation: conversation, Owner: owner, Items: items}); err != nil {
+		return Conversation{}, err
+	}
+	c.conversations[conversation.ID] = conversationEntry{conversation: conversation, owner: owner}
+	return conversation, nil
+}
+
+func (c *Conversations) Get(id, owner string) (Conversation, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry, ok := c.conversations[id]
+	if !ok || entry.owner != owner {
+		return Conversation{}, ErrConversationNotFound
+	}
+	return entry.conversation, nil
+}
+
+// List returns owner's conversations newest first, paginated after the
+// given ID.
+func (c *Conversations) List(owner, after string, limit int) ([]Conversation, bool) {
+	c.mu.Lock()
+	list := make([]Conversation, 0, len(c.conversations))
+	for _, entry := range c.conversations {
+		if entry.owner == owner {
+			list = append(list, entry.conversation)
+		}
+	}
+	c.mu.Unlock()
+
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].CreatedAt != list[j].CreatedAt {
+			return list[i].CreatedAt > list[j].CreatedAt
+		}
+		return list[i].ID > list[j].ID
+	})
+	if after != "" {
+		for i, conversation := range list {
+			if conversation.ID == after {
+				list = list[i+1:]
+				break
+			}
+		}
+	}
+	if len(list) > limit {
+		return list[:limit], true
+	}
+	return list, false
+}
+
+// Update replaces a conversation's metadata.
+func (c *Conversations) Update(id, owner string, metadata map[string]string) (Conversation, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	record, err := c.readLocked(id, owner)
+	if err != nil {
+		return Conversation{}, err
+	}
+	if metadata == nil {
+		metadata = map[string]string{}
+	}
+	record.Metadata = metadata
+	if err := c.writeLocked(record); err != nil {
+		return Conversation{}, err
+	}
+	c.conversations[id] = conversationEntry{conversation: record.Conversation, owner: record.Owner}
+	return record.Conversation, nil
+}
+
+func (c *Conversations) Delete(id, owner string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if entry, ok := c.conversations[id]; !ok || entry.owner != owner {
+		return ErrConversationNotFound
+	}
+	if err := os.Remove(c.path(id)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	delete(c.conversations, id)
+	return nil
+}
+
+// Items returns a conversation's items oldest first.
+func (c *Conversations) Items(id, owner string) ([]json.RawMessage, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	record, err := c.readLocked(id, owner)
+	if err != nil {
+		return nil, err
+	}
+	return record.Items, nil
+}
+
+// Append adds normalized items to the end of a conversation.
+func (c *Conversations) Append(id, owner string, items []json.RawMessage) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	record, err := c.readLocked(id, owner)
+	if err != nil {
+		return err
+	}
+	record.Items = append(record.Items, items...)
+	return c.writeLocked(record)
+}
+
+// DeleteItem removes one item from a conversation.
+func (c *Conversations) DeleteItem(id, owner, itemID string) (Conversation, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	record, err := c.readLocked(id, owner)
+	if err != nil {
+		return Conversation{}, err
+	}
+	for i, item := range record.Items {
+		if ItemID(item) == itemID {
+			record.Items = append(record.Items[:i], record.Items[i+1:]...)
+			return record.Conversation, c.writeLocked(record)
+		}
+	}
+	return Conversation{}, ErrItemNotFound
+}
+
+func (c *Conversations) readLocked(id, owner string) (conversationRecord, error) {
+	if entry, ok := c.conversations[id]; !ok || entry.owner != owner {
+		return conversationRecord{}, ErrConversationNotFound
+	}
+	data, err := os.ReadFile(c.path(id))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return conversationRecord{}, ErrConversationNotFound
+		}
+		return conversationRecord{}, err
+	}
+	var record conversationRecord
+	if err := json.Unmarshal(data, &record); err != nil {
+		return conversationRecord{}, err
+	}
+	return record, nil
+}
+
+func (c *Conversations) writeLocked(record conversationRecord) error {
+	if record.Items == nil {
+		record.Items = []json.RawMessage{}
+	}
+	data, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	return fsutil.WriteFileAtomic(c.path(record.ID), data)
+}
+
+func (c *Conversations) path(id string) string {
+	return filepath.Join(c.dir, filepath.Base(id)+".json")
+}
+
+// ItemID returns the id of a stored item.
+func ItemID(item json.RawMessage) string {
+	var fields struct {
+		ID string `json:"id"`
+	}
+	json.Unmarshal(item, &fields)
+	return fields.ID
+}
+
+// ReplayItems prepares stored conversation items as input for the next
+// request: local IDs are removed and reasoning that upstream cannot resume
+// is dropped.
+func ReplayItems(items []json.RawMessage) []json.RawMessage {
+	replay := make([]json.RawMessage, 0, len(items))
+	for _, item := range items {
+		var fields struct {
+			Type             string `json:"type"`
+			EncryptedContent string `json:"encrypted_content"`
+		}
+		json.Unmarshal(item, &fields)
+		if fields.Type == "reasoning" && fields.EncryptedContent == "" {
+			continue
+		}
+		replay = append(replay, StripItemID(item))
+	}
+	return replay
+}
+
+// ItemsToMessages converts stored items into chat messages. Items without a
+// chat form, such as reasoning, are skipped.
+func ItemsToMessages(items []json.RawMessage) ([]copilot.Message, error) {
+	raw, err := json.Marshal(items)
+	if err != nil {
+		return nil, err
+	}
+	parsed, err := ParseInput(raw)
+	if err != nil {
+		return nil, err
+	}
+	return translateInputItems(parsed)
+}
+
+// ItemsFromMessages converts chat messages into normalized items. Leading
+// system messages are request instructions, not conversation turns, and are
+// left out.
+func ItemsFromMessages(messages []copilot.Message) ([]json.RawMessage, error) {
+	req, err := RequestFromChat(copilot.ChatCompletionsPayload{Messages: messages})
+	if err != nil {
+		return nil, err
+	}
+	return NormalizeInput(req.Input)
+}
+
+// OutputItems returns a response's output as items to append to a
+// conversation.
+func OutputItems(response Response) ([]json.RawMessage, error) {
+	items := make([]json.RawMessage, 0, len(response.Output))
+	for _, output := range response.Output {
+		data, err := json.Marshal(output)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, data)
+	}
+	return items, nil
+}
@@ -0,0 +1,343 @@
+package responses
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"internal/fsutil"
+	"internal/logger"
+)
+
+var ErrResponseNotFound = errors.New("response not found")
+
+// maxChainDepth bounds previous_response_id expansion.
+const maxChainDepth = 1000
+
+// StoredResponse is a response together with the input that produced it.
+// Input holds only the items of that request; earlier turns are reached
+// through PreviousResponseID.
+type StoredResponse struct {
+	ID                 string            `json:"id"`
+	PreviousResponseID string            `json:"previous_response_id,omitempty"`
+	Input              []json.RawMessage `json:"input"`
+	Response           json.RawMessage   `json:"response"`
+	CreatedAt          int64             `json:"created_at"`
+	// Owner is the name of the API key that created the response.
+	Owner string `json:"owner,omitempty"`
+}
+
+type StoreOptions struct {
+	TTL        time.Duration
+	MaxEntries int
+	MaxBytes   int64
+}
+
+type storeEntry struct {
+	size      int64
+	createdAt int64
+	owner     string
+}
+
+// Store keeps responses in the app directory, evicting expired entries and
+// the oldest entries once the count or size limit is exceeded.
+type Store struct {
+	dir  string
+	opts StoreOptions
+
+	mu      sync.Mutex
+	entries map[string]storeEntry
+	bytes   int64
+}
+
+func NewStore(dir string, opts StoreOptions) (*Store, error) {
+	s := &Store{dir: dir, opts: opts, entries: make(map[string]storeEntry)}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return s, nil
+		}
+		return nil, err
+	}
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasSuffix(name, ".json") || strings.HasPrefix(name, ".") {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			return nil, err
+		}
+		var stored StoredResponse
+		if err := json.Unmarshal(data, &stored); err != nil || stored.ID == "" {
+			logger.Warn("Skipping unreadable stored response %s", name)
+			continue
+		}
+		s.entries[stored.ID] = storeEntry{size: int64(len(data)), createdAt: stored.CreatedAt, owner: stored.Owner}
+		s.bytes += int64(len(data))
+	}
+
+	s.mu.Lock()
+	s.evictLocked()
+	s.mu.Unlock()
+	return s, nil
+}
+
+// Save stores a response, replacing any earlier version with the same ID.
+func (s *Store) Save(stored StoredResponse) error {
+	if stored.CreatedAt == 0 {
+		stored.CreatedAt = time.Now().Unix()
+	}
+	data, err := json.Marshal(stored)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := fsutil.WriteFileAtomic(s.path(stored.ID), data); err != nil {
+		return err
+	}
+	if previous, ok := s.entries[stored.ID]; ok {
+		s.bytes -= previous.size
+	}
+	s.entries[stored.ID] = storeEntry{size: int64(len(data)), createdAt: stored.CreatedAt, owner: stored.Owner}
+	s.bytes += int64(len(data))
+	s.evictLocked()
+	return nil
+}
+
+// Get returns response id if it was created by owner.
+func (s *Store) Get(id, owner string) (StoredResponse, error) {
+	s.mu.Lock()
+	entry, ok := s.entries[id]
+	if ok && s.expired(entry) {
+		s.removeLocked(id)
+		ok = false
+	}
+	ok = ok && entry.owner == owner
+	s.mu.Unlock()
+	if !ok {
+		return StoredResponse{}, ErrResponseNotFound
+	}
+
+	data, err := os.ReadFile(s.path(id))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return StoredResponse{}, ErrResponseNotFound
+		}
+		return StoredResponse{}, err
+	}
+	var stored StoredResponse
+	if err := json.Unmarshal(data, &stored); err != nil {
+		return StoredResponse{}, err
+	}
+	return stored, nil
+}
+
+func (s *Store) Delete(id, owner string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if entry, ok := s.entries[id]; !ok || entry.owner != owner {
+		return ErrResponseNotFound
+	}
+	s.removeLocked(id)
+	return nil
+}
+
+// Conversation rebuilds the input items that precede a new request chained
+// to id: every earlier request's input followed by the output it produced.
+// Every response in the chain must belong to owner.
+func (s *Store) Conversation(id, owner string) ([]json.RawMessage, error) {
+	var chain []StoredResponse
+	for next := id; next != ""; {
+		if len(chain) >= maxChainDepth {
+			return nil, fmt.Errorf("previous_response_id chain is longer than %d responses", maxChainDepth)
+		}
+		stored, err := s.Get(next, owner)
+		if err != nil {
+			return nil, fmt.Errorf("previous response %s: %w", next, err)
+		}
+		chain = append(chain, stored)
+		next = stored.PreviousResponseID
+	}
+
+	var items []json.RawMessage
+	for i := len(chain) - 1; i >= 0; i-- {
+		for _, item := range chain[i].Input {
+			items = append(items, StripItemID(item))
+		}
+		var response struct {
+			Output []json.RawMessage `json:"output"`
+		}
+		if err := json.Unmarshal(chain[i].Response, &response); err != nil {
+			return nil, err
+		}
+		for _, item := range response.Output {
+			if replay, ok := replayOutputItem(item); ok {
+				items = append(items, replay)
+			}
+		}
+	}
+	return items, nil
+}
+
+// NormalizeInput converts a request's input into item objects with IDs, as
+// listed by the input_items endpoint.
+func NormalizeInput(raw json.RawMessage) ([]json.RawMessage, error) {
+	if len(raw) == 0 || string(raw) == "null" {
+		return nil, nil
+	}
+
+	var text string
+	if err := json.Unmarshal(raw, &text); err == nil {
+		item, err := json.Marshal(map[string]any{
+			"type":    "message",
+			"id":      NewID("msg_"),
+			"role":    "user",
+			"status":  "completed",
+			"content": []map[string]any{{"type": "input_text", "text": text}},
+		})
+		return []json.RawMessage{item}, err
+	}
+
+	var items []map[string]any
+	if err := json.Unmarshal(raw, &items); err != nil {
+		return nil, fmt.Errorf("invalid input: %w", err)
+	}
+	normalized := make([]json.RawMessage, 0, len(items))
+	for _, item := range items {
+		itemType, _ := item["type"].(string)
+		if itemType == "" {
+			itemType = "message"
+			item["type"] = itemType
+		}
+		if itemType == "message" {
+			if content, ok := item["content"].(string); ok {
+				partType := "input_text"
+				if item["role"] == "assistant" {
+					partType = "output_text"
+				}
+				item["content"] = []map[string]any{{"type": partType, "text": content}}
+			}
+		}
+		if _, ok := item["id"]; !ok {
+			item["id"] = NewID(itemIDPrefix(itemType))
+		}
+		data, err := json.Marshal(item)
+		if err != nil {
+			return nil, err
+		}
+		normalized = append(normalized, data)
+	}
+	return normalized, nil
+}
+
+func itemIDPrefix(itemType string) string {
+	switch itemType {
+	case "function_call":
+		return "fc_"
+	case "function_call_output":
+		return "fco_"
+	default:
+		return "msg_"
+	}
+}
+
+// replayOutputItem turns an output item into an input item for the next
+// turn. Reasoning without encrypted content cannot be replayed upstream and
+// built-in tool calls have no input form, so both are dropped.
+func replayOutputItem(raw json.RawMessage) (json.RawMessage, bool) {
+	var item map[string]any
+	if err := json.Unmarshal(raw, &item); err != nil {
+		return nil, false
+	}
+	switch item["type"] {
+	case "message", "function_call":
+		delete(item, "id")
+		delete(item, "status")
+	case "reasoning":
+		if encrypted, _ := item["encrypted_content"].(string); encrypted == "" {
+			return nil, false
+		}
+	default:
+		return nil, false
+	}
+	data, err := json.Marshal(item)
+	if err != nil {
+		return nil, false
+	}
+	return data, true
+}
+
+// StripItemID removes locally assigned IDs, which upstream would try to
+// resolve against its own (disabled) storage.
+func StripItemID(raw json.RawMessage) json.RawMessage {
+	var item map[string]any
+	if err := json.Unmarshal(raw, &item); err != nil {
+		return raw
+	}
+	if item["type"] == "reasoning" {
+		return raw
+	}
+	delete(item, "id")
+	delete(item, "status")
+	data, err := json.Marshal(item)
+	if err != nil {
+		return raw
+	}
+	return data
+}
+
+func (s *Store) expired(entry storeEntry) bool {
+	return s.opts.TTL > 0 && time.Since(time.Unix(entry.createdAt, 0)) > s.opts.TTL
+}
+
+func (s *Store) evictLocked() {
+	type aged struct {
+		id        string
+		createdAt int64
+	}
+	ordered := make([]aged, 0, len(s.entries))
+	for id, entry := range s.entries {
+		if s.expired(entry) {
+			s.removeLocked(id)
+			continue
+		}
+		ordered = append(ordered, aged{id, entry.createdAt})
+	}
+	sort.Slice(ordered, func(i, j int) bool { return ordered[i].createdAt < ordered[j].createdAt })
+
+	for _, oldest := range ordered {
+		overCount := s.opts.MaxEntries > 0 && len(s.entries) > s.opts.MaxEntries
+		overSize := s.opts.MaxBytes > 0 && s.bytes > s.opts.MaxBytes
+		if !overCount && !overSize {
+			break
+		}
+		logger.Debug("Evicting stored response %s", oldest.id)
+		s.removeLocked(oldest.id)
+	}
+}
+
+func (s *Store) removeLocked(id string) {
+	entry, ok := s.entries[id]
+	if !ok {
+		return
+	}
+	if err := os.Remove(s.path(id)); err != nil && !os.IsNotExist(err) {
+		logger.Warn("Failed to remove stored response %s: %v", id, err)
+	}
+	s.bytes -= entry.size
+	delete(s.entries, id)
+}
+
+func (s *Store) path(id string) string {
+	return filepath.Join(s.dir, filepath.Base(id)+".json")
+}
@@ -0,0 +1,249 @@
+package responses
+
+import (
+	"encoding/json"
+	"sort"
+	"strings"
+
+	"internal/services/copilot"
+)
+
+// StreamState tracks the response being assembled from chat completion
+// chunks.
+type StreamState struct {
+	response     Response
+	started      bool
+	sequence     int
+	finishReason string
+
+	// message is the output index of the open message item, or -1.
+	message int
+	text    strings.Builder
+
+	// calls maps chat tool call indexes to output indexes.
+	calls map[int]int
+}
+
+func NewStreamState(req Request) *StreamState {
+	return &StreamState{
+		response: NewResponse(req, req.Model),
+		message:  -1,
+		calls:    make(map[int]int),
+	}
+}
+
+// TranslateChunk converts one chat completion chunk into Responses events.
+func (s *StreamState) TranslateChunk(chunk copilot.ChatCompletionChunk) ([]SSEEvent, error) {
+	var events []SSEEvent
+	emit := func(eventType string, fields map[string]any) error {
+		event, err := s.event(eventType, fields)
+		if err != nil {
+			return err
+		}
+		events = append(events, event)
+		return nil
+	}
+
+	if !s.started {
+		s.started = true
+		if chunk.Model != "" {
+			s.response.Model = chunk.Model
+		}
+		snapshot := s.response
+		if err := emit("response.created", map[string]any{"response": snapshot}); err != nil {
+			return nil, err
+		}
+		if err := emit("response.in_progress", map[string]any{"response": snapshot}); err != nil {
+			return nil, err
+		}
+	}
+
+	if chunk.Usage != nil {
+		s.response.Usage = translateUsage(chunk.Usage.PromptTokens, chunk.Usage.CompletionTokens, chunk.Usage.PromptTokensDetails, chunk.Usage.CompletionTokensDetails)
+	}
+
+	for _, choice := range chunk.Choices {
+		if choice.Delta.Content != nil && *choice.Delta.Content != "" {
+			if s.message < 0 {
+				if err := s.openMessage(emit); err != nil {
+					return nil, err
+				}
+			}
+			s.text.WriteString(*choice.Delta.Content)
+			if err := emit("response.output_text.delta", map[string]any{
+				"item_id":       s.response.Output[s.message].ID,
+				"output_index":  s.message,
+				"content_index": 0,
+				"delta":         *choice.Delta.Content,
+			}); err != nil {
+				return nil, err
+			}
+		}
+
+		for _, call := range choice.Delta.ToolCalls {
+			index, ok := s.calls[call.Index]
+			if !ok {
+				if s.message >= 0 {
+					if err := s.closeMessage(emit); err != nil {
+						return nil, err
+					}
+				}
+				index = len(s.response.Output)
+				s.calls[call.Index] = index
+				s.response.Output = append(s.response.Output, OutputItem{
+					Type:   "function_call",
+					ID:     NewID("fc_"),
+					Status: "in_progress",
+					CallID: call.ID,
+					Name:   call.Function.Name,
+				})
+				if err := emit("response.output_item.added", map[string]any{
+					"output_index": index,
+					"item":         s.response.Output[index],
+				}); err != nil {
+					return nil, err
+				}
+			}
+			if call.Function.Arguments == "" {
+				continue
+			}
+			s.response.Output[index].Arguments += call.Function.Arguments
+			if err := emit("response.function_call_arguments.delta", map[string]any{
+				"item_id":      s.response.Output[index].ID,
+				"output_index": index,
+				"delta":        call.Function.Arguments,
+			}); err != nil {
+				return nil, err
+			}
+		}
+
+		if choice.FinishReason != nil && *choice.FinishReason != "" && s.finishReason != "length" {
+			s.finishReason = *choice.FinishReason
+		}
+	}
+
+	return events, nil
+}
+
+// Finish closes open items and emits the terminal event. It is called once
+// the upstream stream has ended.
+func (s *StreamState) Finish() ([]SSEEvent, error) {
+	var events []SSEEvent
+	emit := func(eventType string, fields map[string]any) error {
+		event, err := s.event(eventType, fields)
+		if err != nil {
+			return err
+		}
+		events = append(events, event)
+		return nil
+	}
+
+	if s.message >= 0 {
+		if err := s.closeMessage(emit); err != nil {
+			return nil, err
+		}
+	}
+
+	indexes := make([]int, 0, len(s.calls))
+	for _, index := range s.calls {
+		indexes = append(indexes, index)
+	}
+	sort.Ints(indexes)
+	for _, index := range indexes {
+		item := &s.response.Output[index]
+		item.Status = "completed"
+		if err := emit("response.function_call_arguments.done", map[string]any{
+			"item_id":      item.ID,
+			"output_index": index,
+			"arguments":    item.Arguments,
+		}); err != nil {
+			return nil, err
+		}
+		if err := emit("response.output_item.done", map[string]any{
+			"output_index": index,
+			"item":         *item,
+		}); err != nil {
+			return nil, err
+		}
+	}
+	s.calls = make(map[int]int)
+
+	finish(&s.response, s.finishReason)
+	eventType := "response.completed"
+	if s.response.Status == "incomplete" {
+		eventType = "response.incomplete"
+	}
+	if err := emit(eventType, map[string]any{"response": s.response}); err != nil {
+		return nil, err
+	}
+	return events, nil
+}
+
+// Response returns the response assembled so far.
+func (s *StreamState) Response() Response {
+	return s.response
+}
+
+func (s *StreamState) openMessage(emit func(string, map[string]any) error) error {
+	s.message = len(s.response.Output)
+	s.text.Reset()
+	s.response.Output = append(s.response.Output, OutputItem{
+		Type:   "message",
+		ID:     NewID("msg_"),
+		Status: "in_progress",
+		Role:   "assistant",
+	})
+	if err := emit("response.output_item.added", map[string]any{
+		"output_index": s.message,
+		"item":         s.response.Output[s.message],
+	}); err != nil {
+		return err
+	}
+	return emit("response.content_part.added", map[string]any{
+		"item_id":       s.response.Output[s.message].ID,
+		"output_index":  s.message,
+		"content_index": 0,
+		"part":          OutputContent{Type: "output_text", Text: "", Annotations: []any{}},
+	})
+}
+
+func (s *StreamState) closeMessage(emit func(string, map[string]any) error) error {
+	index := s.message
+	item := &s.response.Output[index]
+	part := OutputContent{Type: "output_text", Text: s.text.String(), Annotations: []any{}}
+	item.Content = []OutputContent{part}
+	item.Status = "completed"
+	s.message = -1
+
+	if err := emit("response.output_text.done", map[string]any{
+		"item_id":       item.ID,
+		"output_index":  index,
+		"content_index": 0,
+		"text":          part.Text,
+	}); err != nil {
+		return err
+	}
+	if err := emit("response.content_part.done", map[string]any{
+		"item_id":       item.ID,
+		"output_index":  index,
+		"content_index": 0,
+		"part":          part,
+	}); err != nil {
+		return err
+	}
+	return emit("response.output_item.done", map[string]any{
+		"output_index": index,
+		"item":         *item,
+	})
+}
+
+func (s *StreamState) event(eventType string, fields map[string]any) (SSEEvent, error) {
+	fields["type"] = eventType
+	fields["sequence_number"] = s.sequence
+	s.sequence++
+	data, err := json.Marshal(fields)
+	if err != nil {
+		return SSEEvent{}, err
+	}
+	return SSEEvent{Type: eventType, Data: data}, nil
+}
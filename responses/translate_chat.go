@@ -0,0 +1,256 @@
+package responses
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"internal/logger"
+	"internal/services/copilot"
+)
+
+// TranslateToChat converts a Responses request into a chat completions
+// payload. Built-in tools and reasoning items have no chat equivalent and are
+// dropped.
+func TranslateToChat(req Request) (copilot.ChatCompletionsPayload, error) {
+	payload := copilot.ChatCompletionsPayload{
+		Model:             req.Model,
+		Temperature:       req.Temperature,
+		TopP:              req.TopP,
+		MaxTokens:         req.MaxOutputTokens,
+		ParallelToolCalls: req.ParallelToolCalls,
+		User:              req.User,
+	}
+
+	if req.Instructions != nil && *req.Instructions != "" {
+		payload.Messages = append(payload.Messages, textMessage("system", *req.Instructions))
+	}
+
+	items, err := ParseInput(req.Input)
+	if err != nil {
+		return payload, err
+	}
+	messages, err := translateInputItems(items)
+	if err != nil {
+		return payload, err
+	}
+	payload.Messages = append(payload.Messages, messages...)
+
+	for _, tool := range req.Tools {
+		if tool.Type != "function" {
+			logger.Warn("Dropping unsupported %s tool for chat completions model %s", tool.Type, req.Model)
+			continue
+		}
+		var parameters interface{}
+		if len(tool.Parameters) > 0 {
+			parameters = tool.Parameters
+		}
+		payload.Tools = append(payload.Tools, copilot.Tool{
+			Type: "function",
+			Function: copilot.Function{
+				Name:        tool.Name,
+				Description: tool.Description,
+				Parameters:  parameters,
+			},
+		})
+	}
+
+	if len(payload.Tools) > 0 {
+		choice, err := translateToolChoice(req.ToolChoice)
+		if err != nil {
+			return payload, err
+		}
+		payload.ToolChoice = choice
+	} else {
+		payload.ParallelToolCalls = nil
+	}
+
+	if req.Reasoning != nil {
+		payload.ReasoningEffort = req.Reasoning.Effort
+	}
+
+	if req.Text != nil && req.Text.Format != nil {
+		switch req.Text.Format.Type {
+		case "json_object":
+			payload.ResponseFormat = &copilot.ResponseFormat{Type: "json_object"}
+		case "json_schema":
+			payload.ResponseFormat = &copilot.ResponseFormat{
+				Type: "json_schema",
+				JSONSchema: &copilot.JSONSchemaFormat{
+					Name:        req.Text.Format.Name,
+					Description: req.Text.Format.Description,
+					Schema:      req.Text.Format.Schema,
+					Strict:      req.Text.Format.Strict,
+				},
+			}
+		}
+	}
+
+	if req.Stream != nil && *req.Stream {
+		stream, includeUsage := true, true
+		payload.Stream = &stream
+		payload.StreamOptions = &copilot.StreamOptions{IncludeUsage: &includeUsage}
+	}
+
+	return payload, nil
+}
+
+// ParseInput accepts either a plain string or an array of input items.
+func ParseInput(raw json.RawMessage) ([]InputItem, error) {
+	if len(raw) == 0 || string(raw) == "null" {
+		return nil, nil
+	}
+
+	var text string
+	if err := json.Unmarshal(raw, &text); err == nil {
+		content, _ := json.Marshal(text)
+		return []InputItem{{Type: "message", Role: "user", Content: content}}, nil
+	}
+
+	var items []InputItem
+	if err := json.Unmarshal(raw, &items); err != nil {
+		return nil, fmt.Errorf("invalid input: %w", err)
+	}
+	return items, nil
+}
+
+func translateInputItems(items []InputItem) ([]copilot.Message, error) {
+	var messages []copilot.Message
+	for _, item := range items {
+		switch item.Type {
+		case "", "message":
+			message, err := translateInputMessage(item)
+			if err != nil {
+				return nil, err
+			}
+			messages = append(messages, message)
+
+		case "function_call":
+			call := copilot.ToolCall{
+				ID:   item.CallID,
+				Type: "function",
+				Function: copilot.ToolCallFunction{
+					Name:      item.Name,
+					Arguments: item.Arguments,
+				},
+			}
+			// Parallel calls arrive as consecutive items but belong to one
+			// assistant turn.
+			if last := len(messages) - 1; last >= 0 && messages[last].Role == "assistant" {
+				messages[last].ToolCalls = append(messages[last].ToolCalls, call)
+				continue
+			}
+			messages = append(messages, copilot.Message{
+				Role:      "assistant",
+				ToolCalls: []copilot.ToolCall{call},
+			})
+
+		case "function_call_output":
+			callID := item.CallID
+			message := textMessage("tool", outputText(item.Output))
+			message.ToolCallID = &callID
+			messages = append(messages, message)
+
+		default:
+			logger.Debug("Skipping %s input item for chat completions", item.Type)
+		}
+	}
+	return messages, nil
+}
+
+func translateInputMessage(item InputItem) (copilot.Message, error) {
+	role := item.Role
+	if role == "developer" {
+		role = "system"
+	}
+
+	var text string
+	if err := json.Unmarshal(item.Content, &text); err == nil {
+		return textMessage(role, text), nil
+	}
+
+	var parts []InputContent
+	if err := json.Unmarshal(item.Content, &parts); err != nil {
+		return copilot.Message{}, fmt.Errorf("invalid content for %s message: %w", item.Role, err)
+	}
+
+	var texts []string
+	var chatParts []copilot.ContentPart
+	hasImage := false
+	for _, part := range parts {
+		switch part.Type {
+		case "input_text", "output_text", "text":
+			value := part.Text
+			texts = append(texts, value)
+			chatParts = append(chatParts, copilot.ContentPart{Type: "text", Text: &value})
+		case "input_image":
+			if part.ImageURL == "" {
+				return copilot.Message{}, fmt.Errorf("input_image by file_id is not supported for chat completions models")
+			}
+			hasImage = true
+			chatParts = append(chatParts, copilot.ContentPart{
+				Type:     "image_url",
+				ImageURL: &copilot.ContentImage{URL: part.ImageURL, Detail: part.Detail},
+			})
+		case "refusal":
+		default:
+			return copilot.Message{}, fmt.Errorf("%s content is not supported for chat completions models", part.Type)
+		}
+	}
+
+	if hasImage && role == "user" {
+		return copilot.Message{Role: role, Content: copilot.MessageContent{Parts: chatParts}}, nil
+	}
+	return textMessage(role, strings.Join(texts, "\n\n")), nil
+}
+
+// outputText flattens a function_call_output, which is either a string or a
+// list of input_text parts.
+func outputText(raw json.RawMessage) string {
+	var text string
+	if err := json.Unmarshal(raw, &text); err == nil {
+		return text
+	}
+	var parts []InputContent
+	if err := json.Unmarshal(raw, &parts); err == nil {
+		var texts []string
+		for _, part := range parts {
+			if part.Text != "" {
+				texts = append(texts, part.Text)
+			}
+		}
+		return strings.Join(texts, "\n\n")
+	}
+	return string(raw)
+}
+
+func translateToolChoice(raw json.RawMessage) (*copilot.ToolChoice, error) {
+	if len(raw) == 0 || string(raw) == "null" {
+		return nil, nil
+	}
+
+	var mode string
+	if err := json.Unmarshal(raw, &mode); err == nil {
+		return &copilot.ToolChoice{StringValue: &mode}, nil
+	}
+
+	var choice struct {
+		Type string `json:"type"`
+		Name string `json:"name"`
+	}
+	if err := json.Unmarshal(raw, &choice); err != nil {
+		return nil, fmt.Errorf("invalid tool_choice: %w", err)
+	}
+	if choice.Type != "function" {
+		logger.Debug("Ignoring %s tool_choice for chat completions", choice.Type)
+		return nil, nil
+	}
+	return &copilot.ToolChoice{ObjectValue: &copilot.ToolChoiceValue{
+		Type:     "function",
+		Function: copilot.ToolChoiceFunctionValue{Name: choice.Name},
+	}}, nil
+}
+
+func textMessage(role, text string) copilot.Message {
+	return copilot.Message{Role: role, Content: copilot.MessageContent{StringValue: &text}}
+}
@@ -0,0 +1,137 @@
+package responses
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"strings"
+	"time"
+
+	"internal/services/copilot"
+)
+
+// NewResponse returns an in-progress response that echoes the request
+// parameters, as the Responses API does.
+func NewResponse(req Request, model string) Response {
+	toolChoice := req.ToolChoice
+	if len(toolChoice) == 0 {
+		toolChoice = json.RawMessage(`"auto"`)
+	}
+	tools := req.Tools
+	if tools == nil {
+		tools = []Tool{}
+	}
+	text := TextConfig{Format: &TextFormat{Type: "text"}}
+	if req.Text != nil && req.Text.Format != nil {
+		text = *req.Text
+	}
+	metadata := req.Metadata
+	if metadata == nil {
+		metadata = map[string]string{}
+	}
+
+	return Response{
+		ID:                 NewID("resp_"),
+		Object:             "response",
+		CreatedAt:          time.Now().Unix(),
+		Status:             "in_progress",
+		Model:              model,
+		Output:             []OutputItem{},
+		Instructions:       req.Instructions,
+		MaxOutputTokens:    req.MaxOutputTokens,
+		ParallelToolCalls:  req.ParallelToolCalls == nil || *req.ParallelToolCalls,
+		PreviousResponseID: req.PreviousResponseID,
+		Reasoning:          req.Reasoning,
+		Temperature:        req.Temperature,
+		TopP:               req.TopP,
+		ToolChoice:         toolChoice,
+		Tools:              tools,
+		Text:               text,
+		User:               req.User,
+		Metadata:           metadata,
+	}
+}
+
+// TranslateFromChat converts a chat completion into a completed response.
+// Copilot may split text and tool calls across choices, so all choices are
+// merged into one message item followed by the function calls.
+func TranslateFromChat(completion copilot.ChatCompletionResponse, req Request) Response {
+	response := NewResponse(req, completion.Model)
+
+	var texts []string
+	var calls []OutputItem
+	finishReason := ""
+	for _, choice := range completion.Choices {
+		if text := choice.Message.Content.Text(); text != "" {
+			texts = append(texts, text)
+		}
+		for _, call := range choice.Message.ToolCalls {
+			calls = append(calls, OutputItem{
+				Type:      "function_call",
+				ID:        NewID("fc_"),
+				Status:    "completed",
+				CallID:    call.ID,
+				Name:      call.Function.Name,
+				Arguments: call.Function.Arguments,
+			})
+		}
+		if finishReason == "" || choice.FinishReason == "length" {
+			finishReason = choice.FinishReason
+		}
+	}
+
+	if len(texts) > 0 {
+		response.Output = append(response.Output, OutputItem{
+			Type:    "message",
+			ID:      NewID("msg_"),
+			Status:  "completed",
+			Role:    "assistant",
+			Content: []OutputContent{{Type: "output_text", Text: strings.Join(texts, "\n\n"), Annotations: []any{}}},
+		})
+	}
+	response.Output = append(response.Output, calls...)
+
+	if completion.Usage != nil {
+		response.Usage = translateUsage(completion.Usage.PromptTokens, completion.Usage.CompletionTokens, completion.Usage.PromptTokensDetails, completion.Usage.CompletionTokensDetails)
+	}
+	finish(&response, finishReason)
+	return response
+}
+
+// finish sets the terminal status from a chat finish reason.
+func finish(response *Response, finishReason string) {
+	switch finishReason {
+	case "length":
+		response.Status = "incomplete"
+		response.IncompleteDetails = &IncompleteDetails{Reason: "max_output_tokens"}
+	case "content_filter":
+		response.Status = "incomplete"
+		response.IncompleteDetails = &IncompleteDetails{Reason: "content_filter"}
+	default:
+		response.Status = "completed"
+	}
+}
+
+func translateUsage(prompt, completion int, promptDetails *copilot.PromptTokensDetails, completionDetails *copilot.CompletionTokensDetails) *Usage {
+	usage := &Usage{
+		InputTokens:  prompt,
+		OutputTokens: completion,
+		TotalTokens:  prompt + completion,
+	}
+	if promptDetails != nil {
+		usage.InputTokensDetails.CachedTokens = promptDetails.CachedTokens
+	}
+	if completionDetails != nil {
+		usage.OutputTokensDetails.ReasoningTokens = completionDetails.ReasoningTokens
+	}
+	return usage
+}
+
+// NewID returns a random identifier with the given prefix.
+func NewID(prefix string) string {
+	var b [12]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		panic(err)
+	}
+	return prefix + hex.EncodeToString(b[:])
+}
@@ -0,0 +1,240 @@
+package responses
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"internal/services/copilot"
+)
+
+// Request mirrors the subset of the OpenAI Responses API payload that can be
+// expressed as a chat completion.
+type Request struct {
+	Model              string            `json:"model"`
+	Input              json.RawMessage   `json:"input,omitempty"`
+	Instructions       *string           `json:"instructions,omitempty"`
+	Tools              []Tool            `json:"tools,omitempty"`
+	ToolChoice         json.RawMessage   `json:"tool_choice,omitempty"`
+	Temperature        *float64          `json:"temperature,omitempty"`
+	TopP               *float64          `json:"top_p,omitempty"`
+	MaxOutputTokens    *int              `json:"max_output_tokens,omitempty"`
+	ParallelToolCalls  *bool             `json:"parallel_tool_calls,omitempty"`
+	Stream             *bool             `json:"stream,omitempty"`
+	Reasoning          *Reasoning        `json:"reasoning,omitempty"`
+	Text               *TextConfig       `json:"text,omitempty"`
+	User               *string           `json:"user,omitempty"`
+	Metadata           map[string]string `json:"metadata,omitempty"`
+	Store              *bool             `json:"store,omitempty"`
+	PreviousResponseID *string           `json:"previous_response_id,omitempty"`
+
+	// Extras holds members without a typed field, forwarded unchanged.
+	Extras copilot.Extras `json:"-"`
+}
+
+func (r Request) MarshalJSON() ([]byte, error) {
+	type plain Request
+	return copilot.EncodeWithExtras(plain(r), r.Extras)
+}
+
+func (r *Request) UnmarshalJSON(data []byte) error {
+	type plain Request
+	extras, err := copilot.DecodeWithExtras(data, (*plain)(r))
+	r.Extras = extras
+	return err
+}
+
+type Tool struct {
+	Type        string          `json:"type"`
+	Name        string          `json:"name,omitempty"`
+	Description *string         `json:"description,omitempty"`
+	Parameters  json.RawMessage `json:"parameters,omitempty"`
+	Strict      *bool           `json:"strict,omitempty"`
+}
+
+type Reasoning struct {
+	Effort  *string `json:"effort,omitempty"`
+	Summary *string `json:"summary,omitempty"`
+}
+
+type TextConfig struct {
+	Format *TextFormat `json:"format,omitempty"`
+}
+
+// TextFormat mirrors { type: "text" | "json_object" | "json_schema", ... }.
+type TextFormat struct {
+	Type        string          `json:"type"`
+	Name        string          `json:"name,omitempty"`
+	Description *string         `json:"description,omitempty"`
+	Schema      json.RawMessage `json:"schema,omitempty"`
+	Strict      *bool           `json:"strict,omitempty"`
+}
+
+// InputItem is one element of the input array: a message, a function call
+// made by the model, or the output of that call.
+type InputItem struct {
+	Type      string          `json:"type,omitempty"`
+	ID        string          `json:"id,omitempty"`
+	Role      string          `json:"role,omitempty"`
+	Content   json.RawMessage `json:"content,omitempty"`
+	Status    string          `json:"status,omitempty"`
+	CallID    string          `json:"call_id,omitempty"`
+	Name      string          `json:"name,omitempty"`
+	Arguments string          `json:"arguments,omitempty"`
+	Output    json.RawMessage `json:"output,omitempty"`
+}
+
+// InputContent is one part of a message's content array.
+type InputContent struct {
+	Type     string  `json:"type"`
+	Text     string  `json:"text,omitempty"`
+	ImageURL string  `json:"image_url,omitempty"`
+	FileID   string  `json:"file_id,omitempty"`
+	Detail   *string `json:"detail,omitempty"`
+}
+
+// Response mirrors the OpenAI response object.
+type Response struct {
+	ID                 string             `json:"id"`
+	Object             string             `json:"object"`
+	CreatedAt          int64              `json:"created_at"`
+	Status             string             `json:"status"`
+	Model              string             `json:"model"`
+	Output             []OutputItem       `json:"output"`
+	Usage              *Usage             `json:"usage"`
+	Error              *ResponseError     `json:"error"`
+	IncompleteDetails  *IncompleteDetails `json:"incomplete_details"`
+	Instructions       *string            `json:"instructions"`
+	MaxOutputTokens    *int               `json:"max_output_tokens"`
+	ParallelToolCalls  bool               `json:"parallel_tool_calls"`
+	PreviousResponseID *string            `json:"previous_response_id"`
+	Conversation       *ConversationRef   `json:"conversation,omitempty"`
+	Reasoning          *Reasoning         `json:"reasoning"`
+	Temperature        *float64           `json:"temperature"`
+	TopP               *float64           `json:"top_p"`
+	ToolChoice         json.RawMessage    `json:"tool_choice"`
+	Tools              []Tool             `json:"tools"`
+	Text               TextConfig         `json:"text"`
+	User               *string            `json:"user,omitempty"`
+	Metadata           map[string]string  `json:"metadata"`
+}
+
+type ConversationRef struct {
+	ID string `json:"id"`
+}
+
+type ResponseError struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+type IncompleteDetails struct {
+	Reason string `json:"reason"`
+}
+
+type Usage struct {
+	InputTokens         int                 `json:"input_tokens"`
+	InputTokensDetails  InputTokensDetails  `json:"input_tokens_details"`
+	OutputTokens        int                 `json:"output_tokens"`
+	OutputTokensDetails OutputTokensDetails `json:"output_tokens_details"`
+	TotalTokens         int                 `json:"total_tokens"`
+}
+
+type InputTokensDetails struct {
+	CachedTokens int `json:"cached_tokens"`
+}
+
+type OutputTokensDetails struct {
+	ReasoningTokens int `json:"reasoning_tokens"`
+}
+
+// OutputItem is a message, function_call or reasoning item in a response's
+// output.
+type OutputItem struct {
+	Type             string
+	ID               string
+	Status           string
+	Role             string
+	Content          []OutputContent
+	CallID           string
+	Name             string
+	Arguments        string
+	Summary          []SummaryText
+	EncryptedContent *string
+}
+
+type SummaryText struct {
+	Type string `json:"type"`
+	Text string `json:"text"`
+}
+
+type OutputContent struct {
+	Type        string `json:"type"`
+	Text        string `json:"text"`
+	Annotations []any  `json:"annotations"`
+}
+
+// MarshalJSON emits the fields that belong to the item's type only.
+func (o OutputItem) MarshalJSON() ([]byte, error) {
+	switch o.Type {
+	case "message":
+		content := o.Content
+		if content == nil {
+			content = []OutputContent{}
+		}
+		return json.Marshal(struct {
+			Type    string          `json:"type"`
+			ID      string          `json:"id"`
+			Status  string          `json:"status"`
+			Role    string          `json:"role"`
+			Content []OutputContent `json:"content"`
+		}{o.Type, o.ID, o.Status, o.Role, content})
+	case "function_call":
+		return json.Marshal(struct {
+			Type      string `json:"type"`
+			ID        string `json:"id"`
+			Status    string `json:"status"`
+			CallID    string `json:"call_id"`
+			Name      string `json:"name"`
+			Arguments string `json:"arguments"`
+		}{o.Type, o.ID, o.Status, o.CallID, o.Name, o.Arguments})
+	case "reasoning":
+		summary := o.Summary
+		if summary == nil {
+			summary = []SummaryText{}
+		}
+		return json.Marshal(struct {
+			Type             string        `json:"type"`
+			ID               string        `json:"id"`
+			Summary          []SummaryText `json:"summary"`
+			EncryptedContent *string       `json:"encrypted_content,omitempty"`
+		}{o.Type, o.ID, summary, o.EncryptedContent})
+	default:
+		return nil, fmt.Errorf("unsupported output item type %q", o.Type)
+	}
+}
+
+func (o *OutputItem) UnmarshalJSON(data []byte) error {
+	var raw struct {
+		Type             string          `json:"type"`
+		ID               string          `json:"id"`
+		Status           string          `json:"status"`
+		Role             string          `json:"role"`
+		Content          []OutputContent `json:"content"`
+		CallID           string          `json:"call_id"`
+		Name             string          `json:"name"`
+		Arguments        string          `json:"arguments"`
+		Summary          []SummaryText   `json:"summary"`
+		EncryptedContent *string         `json:"encrypted_content"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	*o = OutputItem(raw)
+	return nil
+}
+
+// SSEEvent is a Responses stream event; Type doubles as the SSE event name.
+type SSEEvent struct {
+	Type string
+	Data json.RawMessage
+}
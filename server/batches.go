@@ -0,0 +1,307 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+
+	"internal/batches"
+	"internal/logger"
+	"internal/paths"
+)
+
+const (
+	defaultBatchListLimit = 20
+	maxBatchListLimit     = 1000
+)
+
+// EnableBatches loads persisted files and batches, resumes unfinished batches
+// and registers the file and batch routes. workers bounds concurrent batch
+// requests across the Anthropic and OpenAI batch APIs.
+func (s *Server) EnableBatches(p paths.Paths, workers int) error {
+	pool := batches.NewPool(workers)
+
+	messageBatches, err := batches.NewMessageBatches(p.MessageBatchesDir, pool, func(ctx context.Context, owner string, body json.RawMessage) (int, json.RawMessage) {
+		return s.dispatchInternal(ctx, s.handleMessages, "/v1/messages", owner, body)
+	})
+	if err != nil {
+		return err
+	}
+	s.messageBatches = messageBatches
+
+	s.mux.Handle("POST /v1/messages/batches", Chain(http.HandlerFunc(s.handleCreateMessageBatch), s.APIKeyMiddleware))
+	s.mux.Handle("GET /v1/messages/batches", Chain(http.HandlerFunc(s.handleListMessageBatches), s.APIKeyMiddleware))
+	s.mux.Handle("GET /v1/messages/batches/{id}", Chain(http.HandlerFunc(s.handleGetMessageBatch), s.APIKeyMiddleware))
+	s.mux.Handle("POST /v1/messages/batches/{id}/cancel", Chain(http.HandlerFunc(s.handleCancelMessageBatch), s.APIKeyMiddleware))
+	s.mux.Handle("GET /v1/messages/batches/{id}/results", Chain(http.HandlerFunc(s.handleMessageBatchResults), s.APIKeyMiddleware))
+
+	files, err := batches.NewFiles(p.FilesDir)
+	if err != nil {
+		return err
+	}
+	s.files = files
+
+	openaiBatches, err := batches.NewOpenAIBatches(p.BatchesDir, files, pool, map[string]batches.Executor{
+		"/v1/chat/completions": func(ctx context.Context, owner string, body json.RawMessage) (int, json.RawMessage) {
+			return s.dispatchInternal(ctx, s.handleChatCompletions, "/v1/chat/completions", owner, body)
+		},
+		"/v1/embeddings": func(ctx context.Context, owner string, body json.RawMessage) (int, json.RawMessage) {
+			return s.dispatchInternal(ctx, s.handleEmbeddings, "/v1/embeddings", owner, body)
+		},
+	})
+	if err != nil {
+		return err
+	}
+	s.openaiBatches = openaiBatches
+
+	s.mux.Handle("POST /v1/files", Chain(http.HandlerFunc(s.handleUploadFile), s.APIKeyMiddleware))
+	s.mux.Handle("GET /v1/files", Chain(http.HandlerFunc(s.handleListFiles), s.APIKeyMiddleware))
+	s.mux.Handle("GET /v1/files/{id}", Chain(http.HandlerFunc(s.handleGetFile), s.APIKeyMiddleware))
+	s.mux.Handle("DELETE /v1/files/{id}", Chain(http.HandlerFunc(s.handleDeleteFile), s.APIKeyMiddleware))
+	s.mux.Handle("GET /v1/files/{id}/content", Chain(http.HandlerFunc(s.handleFileContent), s.APIKeyMiddleware))
+
+	s.mux.Handle("POST /v1/batches", Chain(http.HandlerFunc(s.handleCreateBatch), s.APIKeyMiddleware))
+	s.mux.Handle("GET /v1/batches", Chain(http.HandlerFunc(s.handleListBatches), s.APIKeyMiddleware))
+	s.mux.Handle("GET /v1/batches/{id}", Chain(http.HandlerFunc(s.handleGetBatch), s.APIKeyMiddleware))
+	s.mux.Handle("POST /v1/batches/{id}/cancel", Chain(http.HandlerFunc(s.handleCancelBatch), s.APIKeyMiddleware))
+
+	messageBatches.Resume()
+	openaiBatches.Resume()
+	return nil
+}
+
+func (s *Server) handleCreateMessageBatch(w http.ResponseWriter, r *http.Request) {
+	var payload struct {
+		Requests []batches.MessageBatchRequest `json:"requests"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		writeAnthropicError(w, http.StatusBadRequest, "invalid_request_error", err.Error())
+		return
+	}
+
+	if err := s.awaitApproval(r); err != nil {
+		writeMessagesError(w, err)
+		return
+	}
+
+	batch, err := s.messageBatches.Create(ownerName(r), payload.Requests)
+	if err != nil {
+		writeAnthropicError(w, http.StatusBadRequest, "invalid_request_error", err.Error())
+		return
+	}
+	logger.Info("Created message batch %s with %d requests", batch.ID, len(payload.Requests))
+	s.writeMessageBatch(w, r, batch)
+}
+
+func (s *Server) handleListMessageBatches(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	limit := defaultBatchListLimit
+	if raw := query.Get("limit"); raw != "" {
+		value, err := strconv.Atoi(raw)
+		if err != nil || value < 1 || value > maxBatchListLimit {
+			writeAnthropicError(w, http.StatusBadRequest, "invalid_request_error", "limit: must be between 1 and 1000")
+			return
+		}
+		limit = value
+	}
+
+	list, hasMore := s.messageBatches.List(ownerName(r), query.Get("before_id"), query.Get("after_id"), limit)
+	data := make([]batches.MessageBatch, 0, len(list))
+	for _, batch := range list {
+		data = append(data, s.absoluteResultsURL(r, batch))
+	}
+
+	response := map[string]any{
+		"data":     data,
+		"has_more": hasMore,
+		"first_id": nil,
+		"last_id":  nil,
+	}
+	if len(data) > 0 {
+		response["first_id"] = data[0].ID
+		response["last_id"] = data[len(data)-1].ID
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+func (s *Server) handleGetMessageBatch(w http.ResponseWriter, r *http.Request) {
+	batch, err := s.messageBatches.Get(r.PathValue("id"), ownerName(r))
+	if err != nil {
+		writeBatchError(w, err)
+		return
+	}
+	s.writeMessageBatch(w, r, batch)
+}
+
+func (s *Server) handleCancelMessageBatch(w http.ResponseWriter, r *http.Request) {
+	batch, err := s.messageBatches.Cancel(r.PathValue("id"), ownerName(r))
+	if err != nil {
+		writeBatchError(w, err)
+		return
+	}
+	logger.Info("Cancel requested for message batch %s", batch.ID)
+	s.writeMessageBatch(w, r, batch)
+}
+
+func (s *Server) handleMessageBatchResults(w http.ResponseWriter, r *http.Request) {
+	path, err := s.messageBatches.ResultsPath(r.PathValue("id"), ownerName(r))
+	if err != nil {
+		writeBatchError(w, err)
+		return
+	}
+	serveJSONL(w, path)
+}
+
+func (s *Server) writeMessageBatch(w http.ResponseWriter, r *http.Request, batch batches.MessageBatch) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(s.absoluteResultsURL(r, batch))
+}
+
+// absoluteResultsURL qualifies the stored results path with the request host,
+// as SDKs fetch results_url directly.
+func (s *Server) absoluteResultsURL(r *http.Request, batch batches.MessageBatch) batches.MessageBatch {
+	if batch.ResultsURL != nil && strings.HasPrefix(*batch.ResultsURL, "/") {
+		url := requestBaseURL(r) + *batch.ResultsURL
+		batch.ResultsURL = &url
+	}
+	return batch
+}
+
+func requestBaseURL(r *http.Request) string {
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	if forwarded := r.Header.Get("X-Forwarded-Proto"); forwarded != "" {
+		scheme = forwarded
+	}
+	return scheme + "://" + r.Host
+}
+
+func serveJSONL(w http.ResponseWriter, path string) {
+	file, err := os.Open(path)
+	if err != nil && !os.IsNotExist(err) {
+		writeError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/x-jsonl")
+	if file == nil {
+		return
+	}
+	defer file.Close()
+	if _, err := io.Copy(w, file); err != nil {
+		logger.Error("Failed to write results: %v", err)
+	}
+}
+
+func writeBatchError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, batches.ErrNotFound):
+		writeAnthropicError(w, http.StatusNotFound, "not_found_error", err.Error())
+	case errors.Is(err, batches.ErrNotEnded):
+		writeAnthropicError(w, http.StatusBadRequest, "invalid_request_error", err.Error())
+	default:
+		writeMessagesError(w, err)
+	}
+}
+
+func (s *Server) handleCreateBatch(w http.ResponseWriter, r *http.Request) {
+	var params batches.BatchCreateParams
+	if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
+		writeOpenAIError(w, http.StatusBadRequest, "invalid_request_error", err.Error())
+		return
+	}
+
+	if err := s.awaitApproval(r); err != nil {
+		writeError(w, err)
+		return
+	}
+
+	batch, err := s.openaiBatches.Create(ownerName(r), params)
+	if err != nil {
+		writeOpenAIError(w, http.StatusBadRequest, "invalid_request_error", err.Error())
+		return
+	}
+	logger.Info("Created batch %s for %s (%s)", batch.ID, batch.Endpoint, batch.Status)
+	writeJSON(w, batch)
+}
+
+func (s *Server) handleListBatches(w http.ResponseWriter, r *http.Request) {
+	limit, ok := parseOpenAILimit(w, r, 20, 100)
+	if !ok {
+		return
+	}
+	list, hasMore := s.openaiBatches.List(ownerName(r), r.URL.Query().Get("after"), limit)
+	writeJSON(w, openAIList(list, hasMore, func(batch batches.Batch) string { return batch.ID }))
+}
+
+func (s *Server) handleGetBatch(w http.ResponseWriter, r *http.Request) {
+	batch, err := s.openaiBatches.Get(r.PathValue("id"), ownerName(r))
+	if err != nil {
+		writeOpenAIStoreError(w, err)
+		return
+	}
+	writeJSON(w, batch)
+}
+
+func (s *Server) handleCancelBatch(w http.ResponseWriter, r *http.Request) {
+	batch, err := s.openaiBatches.Cancel(r.PathValue("id"), ownerName(r))
+	if err != nil {
+		writeOpenAIStoreError(w, err)
+		return
+	}
+	logger.Info("Cancel requested for batch %s", batch.ID)
+	writeJSON(w, batch)
+}
+
+func writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
+
+// openAIList builds the OpenAI cursor-paginated list envelope.
+func openAIList[T any](data []T, hasMore bool, id func(T) string) map[string]any {
+	if data == nil {
+		data = []T{}
+	}
+	response := map[string]any{
+		"object":   "list",
+		"data":     data,
+		"has_more": hasMore,
+		"first_id": nil,
+		"last_id":  nil,
+	}
+	if len(data) > 0 {
+		response["first_id"] = id(data[0])
+		response["last_id"] = id(data[len(data)-1])
+	}
+	return response
+}
+
+func parseOpenAILimit(w http.ResponseWriter, r *http.Request, fallback, max int) (int, bool) {
+	raw := r.URL.Query().Get("limit")
+	if raw == "" {
+		return fallback, true
+	}
+	value, err := strconv.Atoi(raw)
+	if err != nil || value < 1 || value > max {
+		writeOpenAIError(w, http.StatusBadRequest, "invalid_request_error", "limit: must be between 1 and "+strconv.Itoa(max))
+		return 0, false
+	}
+	return value, true
+}
+
+func writeOpenAIStoreError(w http.ResponseWriter, err error) {
+	if errors.Is(err, batches.ErrBatchNotFound) || errors.Is(err, batches.ErrFileNotFound) {
+		writeOpenAIError(w, http.StatusNotFound, "invalid_request_error", err.Error())
+		return
+	}
+	writeError(w, err)
+}
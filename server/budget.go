@@ -0,0 +1,178 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"internal/budget"
+	"internal/config"
+	"internal/logger"
+)
+
+// EnableBudgets charges requests made with keys that have a daily or monthly
+// budget to the ledger at path, and rejects keys whose budget is used up.
+func (s *Server) EnableBudgets(path string) error {
+	ledger, err := budget.NewLedger(path)
+	if err != nil {
+		return err
+	}
+	s.budgets = ledger
+	return nil
+}
+
+// requestCost returns the premium units a request to model costs. Requests
+// Copilot treats as agent follow-ups are free unless configured otherwise.
+func (s *Server) requestCost(model, initiator string) float64 {
+	cfg := s.config().Budgets
+	if initiator == "agent" && !cfg.ChargeAgentRequests {
+		return 0
+	}
+	if multiplier, ok := cfg.Multipliers[model]; ok {
+		return multiplier
+	}
+	if !s.isPremium(model) {
+		return 0
+	}
+	if info, ok := s.findModel(model); ok && info.Billing != nil && info.Billing.Multiplier > 0 {
+		return info.Billing.Multiplier
+	}
+	return 1
+}
+
+// budgetedKey returns the request's API key if it has a budget to enforce.
+func (s *Server) budgetedKey(r *http.Request) *config.APIKey {
+	key := apiKeyFromContext(r.Context())
+	if s.budgets == nil || key == nil || (key.DailyBudget == nil && key.MonthlyBudget == nil) {
+		return nil
+	}
+	return key
+}
+
+// budgetContextKey holds the request's *budgetReservation.
+type budgetContextKey struct{}
+
+// budgetError rejects a request whose key has used up its budget. It is
+// rendered as is rather than classified like an upstream failure, and never
+// triggers a fallback.
+type budgetError struct {
+	message string
+}
+
+func (e *budgetError) Error() string {
+	return e.message
+}
+
+// budgetReservation is what one request has reserved against its key's
+// budget: cost units for every upstream call it makes.
+type budgetReservation struct {
+	s     *Server
+	key   *config.APIKey
+	model string
+	cost  float64
+	at    time.Time
+
+	mu       sync.Mutex
+	reserved float64
+	spent    budget.Spent
+}
+
+// reserveBudget reserves one upstream call to model at cost units. It returns
+// nil when the request's key has no budget or the call is free.
+func (s *Server) reserveBudget(r *http.Request, model string, cost float64) (*budgetReservation, error) {
+	key := s.budgetedKey(r)
+	if key == nil || cost <= 0 {
+		return nil, nil
+	}
+	b := &budgetReservation{s: s, key: key, model: model, cost: cost, at: time.Now()}
+	return b, b.reserveCalls(1)
+}
+
+// reserveCalls reserves calls more upstream calls, or returns a budgetError
+// when the key cannot afford them.
+func (b *budgetReservation) reserveCalls(calls int) error {
+	if b == nil || calls <= 0 {
+		return nil
+	}
+	units := b.cost * float64(calls)
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	spent, err := b.s.budgets.Reserve(b.key.Name, units, budget.Limits{Daily: b.key.DailyBudget, Monthly: b.key.MonthlyBudget}, time.Now())
+	var exceeded *budget.ExceededError
+	if errors.As(err, &exceeded) {
+		// Report the request's whole cost against what was used before it.
+		message := fmt.Sprintf("API key %q has used %s of its %s budget of %s premium requests; a request to %s costs %s.",
+			b.key.Name, formatUnits(exceeded.Spent-b.reserved), exceeded.Period, formatUnits(exceeded.Limit), b.model, formatUnits(b.reserved+units))
+		logger.Warn("%s", message)
+		return &budgetError{message: message}
+	}
+	if err != nil {
+		logger.Error("Failed to save budget ledger: %v", err)
+	}
+	b.reserved += units
+	b.spent = spent
+	return nil
+}
+
+// refund returns everything reserved, for a request that failed.
+func (b *budgetReservation) refund() {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	spent, err := b.s.budgets.Refund(b.key.Name, b.reserved, b.at, time.Now())
+	if err != nil {
+		logger.Error("Failed to save budget ledger: %v", err)
+	}
+	b.reserved = 0
+	b.spent = spent
+}
+
+// writeHeaders reports what is left of the key's budgets.
+func (b *budgetReservation) writeHeaders(w http.ResponseWriter) {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.key.DailyBudget != nil {
+		w.Header().Set("X-Budget-Daily-Remaining", formatUnits(*b.key.DailyBudget-b.spent.Daily))
+	}
+	if b.key.MonthlyBudget != nil {
+		w.Header().Set("X-Budget-Monthly-Remaining", formatUnits(*b.key.MonthlyBudget-b.spent.Monthly))
+	}
+}
+
+// reserveUpstreamCalls reserves budget for calls upstream calls beyond the
+// first, such as n>1 fan-out or structured output retries.
+func reserveUpstreamCalls(ctx context.Context, calls int) error {
+	b, _ := ctx.Value(budgetContextKey{}).(*budgetReservation)
+	return b.reserveCalls(calls)
+}
+
+// writeBudgetError renders err in the OpenAI or Anthropic error envelope when
+// it is a budgetError.
+func writeBudgetError(w http.ResponseWriter, err error, anthropic bool) bool {
+	var budgetErr *budgetError
+	if !errors.As(err, &budgetErr) {
+		return false
+	}
+	if anthropic {
+		writeAnthropicError(w, http.StatusPaymentRequired, "billing_error", budgetErr.message)
+		return true
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusTooManyRequests)
+	w.Write(openAIErrorBody(budgetErr.message, "insufficient_quota", "budget_exceeded", ""))
+	return true
+}
+
+func formatUnits(value float64) string {
+	return strconv.FormatFloat(value, 'f', -1, 64)
+}
@@ -0,0 +1,101 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"internal/compaction"
+	"internal/config"
+	"internal/logger"
+	"internal/services/copilot"
+)
+
+const compactionHeader = "X-Copilot-Compaction"
+
+const summaryPrompt = "You condense earlier parts of a conversation between a user and an AI assistant. " +
+	"Write a concise summary that preserves goals, decisions, file names, identifiers and open tasks. " +
+	"Do not add commentary."
+
+// compactionMode resolves whether compaction applies to r. The request header
+// takes precedence over the per-key setting.
+func (s *Server) compactionMode(r *http.Request) string {
+	cfg := s.config()
+	switch strings.ToLower(strings.TrimSpace(r.Header.Get(compactionHeader))) {
+	case "":
+	case "off", "false", "0":
+		return ""
+	case config.CompactionTruncate:
+		return config.CompactionTruncate
+	case config.CompactionSummarize:
+		return config.CompactionSummarize
+	default:
+		return cfg.Compaction.Mode
+	}
+
+	if key := apiKeyFromContext(r.Context()); key != nil && key.Compaction {
+		return cfg.Compaction.Mode
+	}
+	return ""
+}
+
+// compact shortens payload when compaction is enabled for r and the prompt
+// exceeds the model's limit. Report headers are set on w.
+func (s *Server) compact(w http.ResponseWriter, r *http.Request, payload copilot.ChatCompletionsPayload) (copilot.ChatCompletionsPayload, error) {
+	mode := s.compactionMode(r)
+	if mode == "" {
+		return payload, nil
+	}
+
+	model, ok := s.findModel(payload.Model)
+	if !ok || model.Capabilities.Limits.MaxPromptTokens == nil {
+		logger.Debug("Compaction skipped: no prompt limit known for model %s", payload.Model)
+		return payload, nil
+	}
+
+	cfg := s.config()
+	compacted, result, err := compaction.Compact(r.Context(), payload, *model.Capabilities.Limits.MaxPromptTokens, compaction.Options{
+		Mode:         mode,
+		SummaryModel: cfg.Compaction.SummaryModel,
+		KeepRecent:   cfg.Compaction.KeepRecent,
+		Summarize:    s.summarize,
+	})
+	if err != nil {
+		return payload, err
+	}
+
+	if result.Compacted() {
+		logger.Info("Compacted conversation for model %s: ~%d -> ~%d tokens (%d tool outputs trimmed, %d messages dropped)",
+			payload.Model, result.OriginalTokens, result.Tokens, result.TrimmedToolOutputs, result.DroppedMessages)
+		w.Header().Set("X-Compaction-Mode", mode)
+		w.Header().Set("X-Compaction-Dropped-Tokens", strconv.Itoa(result.DroppedTokens()))
+		w.Header().Set("X-Compaction-Dropped-Messages", strconv.Itoa(result.DroppedMessages))
+		w.Header().Set("X-Compaction-Trimmed-Tool-Outputs", strconv.Itoa(result.TrimmedToolOutputs))
+	}
+	return compacted, nil
+}
+
+func (s *Server) summarize(ctx context.Context, model string, transcript string) (string, error) {
+	system := summaryPrompt
+	stream := false
+	payload := copilot.ChatCompletionsPayload{
+		Model: model,
+		Messages: []copilot.Message{
+			{Role: "system", Content: copilot.MessageContent{StringValue: &system}},
+			{Role: "user", Content: copilot.MessageContent{StringValue: &transcript}},
+		},
+		Stream: &stream,
+	}
+
+	result, err := s.createChatCompletions(ctx, payload)
+	if err != nil {
+		return "", err
+	}
+	completion, ok := result.(copilot.ChatCompletionResponse)
+	if !ok || len(completion.Choices) == 0 {
+		return "", fmt.Errorf("unexpected summary response")
+	}
+	return completion.Choices[0].Message.Content.Text(), nil
+}
@@ -0,0 +1,303 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"internal/logger"
+	"internal/responses"
+	"internal/services/copilot"
+)
+
+const (
+	conversationHeader = "X-Copilot-Conversation"
+
+	defaultConversationListLimit = 20
+	maxConversationListLimit     = 100
+	maxConversationItemsPerAdd   = 20
+)
+
+// conversationTurn is a chat or messages request appended to a stored
+// conversation once the assistant has answered.
+type conversationTurn struct {
+	id    string
+	owner string
+	model string
+	input []json.RawMessage
+}
+
+// EnableConversations registers the local /v1/conversations API backed by
+// dir.
+func (s *Server) EnableConversations(dir string) error {
+	conversations, err := responses.NewConversations(dir)
+	if err != nil {
+		return err
+	}
+	s.conversations = conversations
+
+	s.mux.Handle("POST /v1/conversations", Chain(http.HandlerFunc(s.handleCreateConversation), s.APIKeyMiddleware))
+	s.mux.Handle("GET /v1/conversations", Chain(http.HandlerFunc(s.handleListConversations), s.APIKeyMiddleware))
+	s.mux.Handle("GET /v1/conversations/{id}", Chain(http.HandlerFunc(s.handleGetConversation), s.APIKeyMiddleware))
+	s.mux.Handle("POST /v1/conversations/{id}", Chain(http.HandlerFunc(s.handleUpdateConversation), s.APIKeyMiddleware))
+	s.mux.Handle("DELETE /v1/conversations/{id}", Chain(http.HandlerFunc(s.handleDeleteConversation), s.APIKeyMiddleware))
+	s.mux.Handle("POST /v1/conversations/{id}/items", Chain(http.HandlerFunc(s.handleAddConversationItems), s.APIKeyMiddleware))
+	s.mux.Handle("GET /v1/conversations/{id}/items", Chain(http.HandlerFunc(s.handleListConversationItems), s.APIKeyMiddleware))
+	s.mux.Handle("GET /v1/conversations/{id}/items/{item_id}", Chain(http.HandlerFunc(s.handleGetConversationItem), s.APIKeyMiddleware))
+	s.mux.Handle("DELETE /v1/conversations/{id}/items/{item_id}", Chain(http.HandlerFunc(s.handleDeleteConversationItem), s.APIKeyMiddleware))
+	return nil
+}
+
+func (s *Server) handleCreateConversation(w http.ResponseWriter, r *http.Request) {
+	var payload struct {
+		Metadata map[string]string `json:"metadata"`
+		Items    json.RawMessage   `json:"items"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		writeOpenAIError(w, http.StatusBadRequest, "invalid_request_error", err.Error())
+		return
+	}
+	items, err := responses.NormalizeInput(payload.Items)
+	if err != nil {
+		writeOpenAIError(w, http.StatusBadRequest, "invalid_request_error", err.Error())
+		return
+	}
+	if len(items) > maxConversationItemsPerAdd {
+		writeOpenAIError(w, http.StatusBadRequest, "invalid_request_error", "items: at most 20 items can be added at a time")
+		return
+	}
+
+	conversation, err := s.conversations.Create(ownerName(r), payload.Metadata, items)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	logger.Info("Created conversation %s", conversation.ID)
+	writeJSON(w, conversation)
+}
+
+func (s *Server) handleListConversations(w http.ResponseWriter, r *http.Request) {
+	limit, ok := parseOpenAILimit(w, r, defaultConversationListLimit, maxConversationListLimit)
+	if !ok {
+		return
+	}
+	list, hasMore := s.conversations.List(ownerName(r), r.URL.Query().Get("after"), limit)
+	writeJSON(w, openAIList(list, hasMore, func(c responses.Conversation) string { return c.ID }))
+}
+
+func (s *Server) handleGetConversation(w http.ResponseWriter, r *http.Request) {
+	conversation, err := s.conversations.Get(r.PathValue("id"), ownerName(r))
+	if err != nil {
+		writeConversationError(w, err)
+		return
+	}
+	writeJSON(w, conversation)
+}
+
+func (s *Server) handleUpdateConversation(w http.ResponseWriter, r *http.Request) {
+	var payload struct {
+		Metadata map[string]string `json:"metadata"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		writeOpenAIError(w, http.StatusBadRequest, "invalid_request_error", err.Error())
+		return
+	}
+	conversation, err := s.conversations.Update(r.PathValue("id"), ownerName(r), payload.Metadata)
+	if err != nil {
+		writeConversationError(w, err)
+		return
+	}
+	writeJSON(w, conversation)
+}
+
+func (s *Server) handleDeleteConversation(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	if err := s.conversations.Delete(id, ownerName(r)); err != nil {
+		writeConversationError(w, err)
+		return
+	}
+	logger.Info("Deleted conversation %s", id)
+	writeJSON(w, map[string]any{"id": id, "object": "conversation.deleted", "deleted": true})
+}
+
+func (s *Server) handleAddConversationItems(w http.ResponseWriter, r *http.Request) {
+	var payload struct {
+		Items json.RawMessage `json:"items"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		writeOpenAIError(w, http.StatusBadRequest, "invalid_request_error", err.Error())
+		return
+	}
+	items, err := responses.NormalizeInput(payload.Items)
+	if err != nil {
+		writeOpenAIError(w, http.StatusBadRequest, "invalid_request_error", err.Error())
+		return
+	}
+	if len(items) == 0 || len(items) > maxConversationItemsPerAdd {
+		writeOpenAIError(w, http.StatusBadRequest, "invalid_request_error", "items: between 1 and 20 items must be provided")
+		return
+	}
+
+	if err := s.conversations.Append(r.PathValue("id"), ownerName(r), items); err != nil {
+		writeConversationError(w, err)
+		return
+	}
+	writeJSON(w, openAIList(items, false, responses.ItemID))
+}
+
+func (s *Server) handleListConversationItems(w http.ResponseWriter, r *http.Request) {
+	items, err := s.conversations.Items(r.PathValue("id"), ownerName(r))
+	if err != nil {
+		writeConversationError(w, err)
+		return
+	}
+
+	page, hasMore, ok := pageItems(w, r, items)
+	if !ok {
+		return
+	}
+	writeJSON(w, openAIList(page, hasMore, responses.ItemID))
+}
+
+func (s *Server) handleGetConversationItem(w http.ResponseWriter, r *http.Request) {
+	items, err := s.conversations.Items(r.PathValue("id"), ownerName(r))
+	if err != nil {
+		writeConversationError(w, err)
+		return
+	}
+	itemID := r.PathValue("item_id")
+	for _, item := range items {
+		if responses.ItemID(item) == itemID {
+			w.Header().Set("Content-Type", "application/json")
+			w.Write(item)
+			return
+		}
+	}
+	writeConversationError(w, responses.ErrItemNotFound)
+}
+
+func (s *Server) handleDeleteConversationItem(w http.ResponseWriter, r *http.Request) {
+	conversation, err := s.conversations.DeleteItem(r.PathValue("id"), ownerName(r), r.PathValue("item_id"))
+	if err != nil {
+		writeConversationError(w, err)
+		return
+	}
+	writeJSON(w, conversation)
+}
+
+func writeConversationError(w http.ResponseWriter, err error) {
+	if errors.Is(err, responses.ErrConversationNotFound) || errors.Is(err, responses.ErrItemNotFound) {
+		writeOpenAIError(w, http.StatusNotFound, "invalid_request_error", err.Error())
+		return
+	}
+	writeError(w, err)
+}
+
+// expandConversation prepends the stored history of the conversation named
+// by the X-Copilot-Conversation header to a chat payload. The returned turn
+// is nil when the request does not reference a conversation.
+func (s *Server) expandConversation(r *http.Request, payload copilot.ChatCompletionsPayload) (copilot.ChatCompletionsPayload, *conversationTurn, error) {
+	id := r.Header.Get(conversationHeader)
+	if id == "" || s.conversations == nil {
+		return payload, nil, nil
+	}
+
+	items, err := s.conversations.Items(id, ownerName(r))
+	if err != nil {
+		return payload, nil, err
+	}
+	history, err := responses.ItemsToMessages(items)
+	if err != nil {
+		return payload, nil, err
+	}
+
+	// The history goes after the request's leading system messages, which
+	// are per-request instructions rather than conversation turns.
+	split := 0
+	for split < len(payload.Messages) && (payload.Messages[split].Role == "system" || payload.Messages[split].Role == "developer") {
+		split++
+	}
+	input, err := responses.ItemsFromMessages(payload.Messages)
+	if err != nil {
+		return payload, nil, err
+	}
+
+	expanded := make([]copilot.Message, 0, len(history)+len(payload.Messages))
+	expanded = append(expanded, payload.Messages[:split]...)
+	expanded = append(expanded, history...)
+	expanded = append(expanded, payload.Messages[split:]...)
+	payload.Messages = expanded
+	logger.Debug("Expanded conversation %s into %d messages", id, len(history))
+
+	return payload, &conversationTurn{id: id, owner: ownerName(r), model: payload.Model, input: input}, nil
+}
+
+// appendConversationTurn stores the request's new items and the assistant's
+// output in the conversation.
+func (s *Server) appendConversationTurn(turn *conversationTurn, response responses.Response) {
+	if turn == nil {
+		return
+	}
+	output, err := responses.OutputItems(response)
+	if err != nil {
+		logger.Warn("Failed to encode output for conversation %s: %v", turn.id, err)
+		return
+	}
+	if err := s.conversations.Append(turn.id, turn.owner, append(turn.input, output...)); err != nil {
+		logger.Warn("Failed to append to conversation %s: %v", turn.id, err)
+		return
+	}
+	logger.Debug("Appended %d items to conversation %s", len(turn.input)+len(output), turn.id)
+}
+
+// appendConversationCompletion records a non-streaming chat completion.
+func (s *Server) appendConversationCompletion(turn *conversationTurn, result interface{}) {
+	if turn == nil {
+		return
+	}
+	completion, ok := result.(copilot.ChatCompletionResponse)
+	if !ok {
+		return
+	}
+	s.appendConversationTurn(turn, responses.TranslateFromChat(completion, responses.Request{Model: turn.model}))
+}
+
+// recordConversationStream forwards a chat completion stream unchanged and
+// appends the assembled assistant turn once it ends.
+func (s *Server) recordConversationStream(ctx context.Context, result interface{}, turn *conversationTurn) interface{} {
+	upstream, ok := result.(<-chan copilot.SSEMessage)
+	if turn == nil || !ok {
+		return result
+	}
+
+	out := make(chan copilot.SSEMessage)
+	go func() {
+		defer close(out)
+		state := responses.NewStreamState(responses.Request{Model: turn.model})
+		for msg := range upstream {
+			switch msg.Data {
+			case "":
+			case "[DONE]":
+				// Record before forwarding [DONE] so a client's next turn
+				// sees this one.
+				if _, err := state.Finish(); err != nil {
+					logger.Warn("Failed to assemble turn for conversation %s: %v", turn.id, err)
+				} else {
+					s.appendConversationTurn(turn, state.Response())
+				}
+			default:
+				var chunk copilot.ChatCompletionChunk
+				if err := json.Unmarshal([]byte(msg.Data), &chunk); err == nil {
+					state.TranslateChunk(chunk)
+				}
+			}
+			select {
+			case out <- msg:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return (<-chan copilot.SSEMessage)(out)
+}
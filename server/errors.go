@@ -2,7 +2,10 @@ package server
 
 import (
 	"encoding/json"
+	"fmt"
+	"io"
 	"net/http"
+	"strings"
 
 	appErr "internal/errors"
 	"internal/logger"
@@ -12,28 +15,196 @@ type errorResponse struct {
 	Error any `json:"error"`
 }
 
+// writeError renders err in the OpenAI error envelope. Upstream failures are
+// classified so quota, rate-limit and context-length errors carry the codes
+// OpenAI SDKs look for.
 func writeError(w http.ResponseWriter, err error) {
 	logger.Error("Request failed: %v", err)
-	if httpErr, ok := err.(*appErr.HTTPError); ok {
-		for key, values := range httpErr.Response.Header {
-			for _, v := range values {
-				w.Header().Add(key, v)
-			}
-		}
-		if len(httpErr.Body) > 0 {
-			w.WriteHeader(httpErr.Response.StatusCode)
-			_, _ = w.Write(httpErr.Body)
-			return
-		}
-		w.WriteHeader(httpErr.Response.StatusCode)
+	if writeBudgetError(w, err, false) {
 		return
 	}
+	c := appErr.Classify(err)
+	status, errorType, code := openAIError(c)
+	writeRetryAfter(w, c)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_, _ = w.Write(openAIErrorBody(c.Message, errorType, code, c.Param))
+}
+
+// writeMessagesError renders err in the Anthropic error envelope.
+func writeMessagesError(w http.ResponseWriter, err error) {
+	logger.Error("Request failed: %v", err)
+	if writeBudgetError(w, err, true) {
+		return
+	}
+	c := appErr.Classify(err)
+	status, errorType := anthropicError(c)
+	writeRetryAfter(w, c)
+	writeAnthropicError(w, status, errorType, anthropicMessage(c))
+}
+
+// writeAnthropicError renders an error in the Anthropic API error envelope.
+func writeAnthropicError(w http.ResponseWriter, status int, errorType, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_, _ = w.Write(anthropicErrorBody(errorType, message))
+}
+
+// writeOpenAIError renders an error in the OpenAI API error envelope.
+func writeOpenAIError(w http.ResponseWriter, status int, errorType, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_, _ = w.Write(openAIErrorBody(message, errorType, "", ""))
+}
 
+// writeAuthenticationError rejects a request to path for a missing or
+// unknown API key, in the Anthropic envelope on /v1/messages and the OpenAI
+// one elsewhere.
+func writeAuthenticationError(w http.ResponseWriter, path, message string) {
+	if strings.HasPrefix(path, "/v1/messages") {
+		writeAnthropicError(w, http.StatusUnauthorized, "authentication_error", message)
+		return
+	}
 	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusInternalServerError)
-	payload, _ := json.Marshal(errorResponse{Error: map[string]any{
-		"message": err.Error(),
-		"type":    "error",
-	}})
-	_, _ = w.Write(payload)
+	w.WriteHeader(http.StatusUnauthorized)
+	_, _ = w.Write(openAIErrorBody(message, "authentication_error", "invalid_api_key", ""))
+}
+
+// writeStreamError ends a stream that already sent its headers with an error
+// event in the client's dialect.
+func writeStreamError(w io.Writer, dialect streamDialect, err error) {
+	logger.Error("Upstream stream failed: %v", err)
+	c := appErr.Classify(err)
+	switch dialect {
+	case dialectAnthropic:
+		_, errorType := anthropicError(c)
+		fmt.Fprintf(w, "event: error\ndata: %s\n\n", anthropicErrorBody(errorType, anthropicMessage(c)))
+	case dialectResponses:
+		_, _, code := openAIError(c)
+		var param any
+		if c.Param != "" {
+			param = c.Param
+		}
+		data, _ := json.Marshal(map[string]any{
+			"type":    "error",
+			"code":    code,
+			"message": c.Message,
+			"param":   param,
+		})
+		fmt.Fprintf(w, "event: error\ndata: %s\n\n", data)
+	default:
+		_, errorType, code := openAIError(c)
+		fmt.Fprintf(w, "data: %s\n\n", openAIErrorBody(c.Message, errorType, code, c.Param))
+	}
+}
+
+// openAIError maps a classified error to the status, type and code the
+// OpenAI API uses for it. Codes sent by upstream are kept.
+func openAIError(c appErr.Classified) (int, string, string) {
+	status := c.Status
+	errorType, code := "server_error", c.Code
+	fallback := func(value string) {
+		if code == "" {
+			code = value
+		}
+	}
+	switch c.Kind {
+	case appErr.KindInvalidRequest, appErr.KindNotFound:
+		errorType = "invalid_request_error"
+	case appErr.KindContextLength:
+		status, errorType, code = http.StatusBadRequest, "invalid_request_error", "context_length_exceeded"
+	case appErr.KindRequestTooLarge:
+		errorType = "invalid_request_error"
+		fallback("request_too_large")
+	case appErr.KindAuthentication:
+		errorType = "authentication_error"
+		fallback("invalid_api_key")
+	case appErr.KindPermission:
+		errorType = "permission_error"
+	case appErr.KindRateLimit:
+		errorType = "rate_limit_error"
+		fallback("rate_limit_exceeded")
+	case appErr.KindQuota:
+		status, errorType, code = http.StatusTooManyRequests, "insufficient_quota", "insufficient_quota"
+	case appErr.KindOverloaded:
+		fallback("overloaded")
+	case appErr.KindTimeout:
+		errorType = "timeout_error"
+		fallback("upstream_timeout")
+	}
+	if status == 0 {
+		status = c.Kind.Status()
+	}
+	return status, errorType, code
+}
+
+// anthropicError maps a classified error to the Anthropic status and type.
+func anthropicError(c appErr.Classified) (int, string) {
+	switch c.Kind {
+	case appErr.KindInvalidRequest, appErr.KindContextLength:
+		return http.StatusBadRequest, "invalid_request_error"
+	case appErr.KindAuthentication:
+		return http.StatusUnauthorized, "authentication_error"
+	case appErr.KindPermission:
+		return http.StatusForbidden, "permission_error"
+	case appErr.KindNotFound:
+		return http.StatusNotFound, "not_found_error"
+	case appErr.KindRequestTooLarge:
+		return http.StatusRequestEntityTooLarge, "request_too_large"
+	case appErr.KindRateLimit:
+		return http.StatusTooManyRequests, "rate_limit_error"
+	case appErr.KindQuota:
+		return http.StatusPaymentRequired, "billing_error"
+	case appErr.KindOverloaded:
+		return 529, "overloaded_error"
+	case appErr.KindTimeout:
+		return http.StatusGatewayTimeout, "timeout_error"
+	}
+	if c.Status >= 500 {
+		return c.Status, "api_error"
+	}
+	return http.StatusInternalServerError, "api_error"
+}
+
+// anthropicMessage prefixes context-length errors with the wording Anthropic
+// clients match on to trigger compaction.
+func anthropicMessage(c appErr.Classified) string {
+	if c.Kind == appErr.KindContextLength && !strings.Contains(strings.ToLower(c.Message), "prompt is too long") {
+		return "prompt is too long: " + c.Message
+	}
+	return c.Message
+}
+
+func openAIErrorBody(message, errorType, code, param string) []byte {
+	fields := map[string]any{
+		"message": message,
+		"type":    errorType,
+		"param":   nil,
+		"code":    nil,
+	}
+	if code != "" {
+		fields["code"] = code
+	}
+	if param != "" {
+		fields["param"] = param
+	}
+	payload, _ := json.Marshal(errorResponse{Error: fields})
+	return payload
+}
+
+func anthropicErrorBody(errorType, message string) []byte {
+	payload, _ := json.Marshal(map[string]any{
+		"type": "error",
+		"error": map[string]any{
+			"type":    errorType,
+			"message": message,
+		},
+	})
+	return payload
+}
+
+func writeRetryAfter(w http.ResponseWriter, c appErr.Classified) {
+	if c.RetryAfter != "" {
+		w.Header().Set("Retry-After", c.RetryAfter)
+	}
 }
@@ -0,0 +1,88 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	appErr "internal/errors"
+	"internal/logger"
+	"internal/services/copilot"
+)
+
+// fallbackChain returns model followed by its configured fallbacks. When
+// endpoint is set, fallbacks known not to serve it are left out.
+func (s *Server) fallbackChain(model, endpoint string) []string {
+	chain := []string{model}
+	for _, candidate := range s.config().Fallbacks[model] {
+		if endpoint != "" {
+			if info, ok := s.findModel(candidate); ok && !info.SupportsEndpoint(endpoint) {
+				logger.Debug("Skipping fallback %s: no %s endpoint", candidate, endpoint)
+				continue
+			}
+		}
+		chain = append(chain, candidate)
+	}
+	return chain
+}
+
+// withFallback calls create with each model of chain in turn until one
+// succeeds or fails in a way the next model would not fix. Each model first
+// passes the premium quota policy and reserves its cost against the API key's
+// budget; the reservation is refunded if the model fails. create receives a
+// context through which further upstream calls are reserved. The model that
+// answered is reported in the X-Model-Used header.
+func (s *Server) withFallback(w http.ResponseWriter, r *http.Request, chain []string, messages []copilot.Message, create func(ctx context.Context, model string) (interface{}, error)) (interface{}, error) {
+	for i, candidate := range chain {
+		model, err := s.applyQuotaPolicy(w, candidate)
+		initiator := copilot.ResolveChatInitiator(model, messages)
+		var reservation *budgetReservation
+		if err == nil {
+			reservation, err = s.reserveBudget(r, model, s.requestCost(model, initiator))
+		}
+		var result interface{}
+		if err == nil {
+			result, err = create(context.WithValue(r.Context(), budgetContextKey{}, reservation), model)
+		}
+		usageRecordFromContext(r.Context()).setModel(model, initiator)
+		if err == nil {
+			reservation.writeHeaders(w)
+			w.Header().Set("X-Model-Used", model)
+			return observeUsage(r.Context(), result), nil
+		}
+		reservation.refund()
+		if i == len(chain)-1 || !fallbackEligible(err) {
+			return nil, err
+		}
+		logger.Warn("Model %s failed (%v), falling back to %s", model, err, chain[i+1])
+		s.metrics.fallback(model, chain[i+1])
+	}
+	return nil, nil
+}
+
+// fallbackEligible reports whether err is an upstream failure that another
+// model may not share: rate limits, exhausted quota, overload and 5xx
+// responses, including an open circuit.
+// Problems with the request itself are returned to the client.
+func fallbackEligible(err error) bool {
+	var httpErr *appErr.HTTPError
+	if !errors.As(err, &httpErr) {
+		return false
+	}
+	switch appErr.Classify(err).Kind {
+	case appErr.KindRateLimit, appErr.KindQuota, appErr.KindOverloaded, appErr.KindTimeout, appErr.KindAPI:
+		return true
+	}
+	return false
+}
+
+// withModel replaces the model of a raw request body.
+func withModel(body []byte, model string) ([]byte, error) {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(body, &fields); err != nil {
+		return nil, err
+	}
+	fields["model"], _ = json.Marshal(model)
+	return json.Marshal(fields)
+}
@@ -0,0 +1,86 @@
+package server
+
+import (
+	"io"
+	"net/http"
+	"strings"
+
+	"internal/batches"
+	"internal/logger"
+)
+
+func (s *Server) handleUploadFile(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, batches.MaxFileBytes+1<<20)
+	if err := r.ParseMultipartForm(32 << 20); err != nil {
+		writeOpenAIError(w, http.StatusBadRequest, "invalid_request_error", "expected multipart/form-data with a file: "+err.Error())
+		return
+	}
+	defer r.MultipartForm.RemoveAll()
+
+	purpose := strings.TrimSpace(r.FormValue("purpose"))
+	if purpose == "" {
+		writeOpenAIError(w, http.StatusBadRequest, "invalid_request_error", "purpose: field required")
+		return
+	}
+
+	upload, header, err := r.FormFile("file")
+	if err != nil {
+		writeOpenAIError(w, http.StatusBadRequest, "invalid_request_error", "file: field required")
+		return
+	}
+	defer upload.Close()
+
+	file, err := s.files.Create(ownerName(r), header.Filename, purpose, upload)
+	if err != nil {
+		writeOpenAIError(w, http.StatusBadRequest, "invalid_request_error", err.Error())
+		return
+	}
+	logger.Info("Stored file %s (%s, %d bytes)", file.ID, file.Filename, file.Bytes)
+	writeJSON(w, file)
+}
+
+func (s *Server) handleListFiles(w http.ResponseWriter, r *http.Request) {
+	limit, ok := parseOpenAILimit(w, r, 10000, 10000)
+	if !ok {
+		return
+	}
+	query := r.URL.Query()
+	list, hasMore := s.files.List(ownerName(r), query.Get("purpose"), query.Get("after"), limit)
+	writeJSON(w, openAIList(list, hasMore, func(file batches.File) string { return file.ID }))
+}
+
+func (s *Server) handleGetFile(w http.ResponseWriter, r *http.Request) {
+	file, err := s.files.Get(r.PathValue("id"), ownerName(r))
+	if err != nil {
+		writeOpenAIStoreError(w, err)
+		return
+	}
+	writeJSON(w, file)
+}
+
+func (s *Server) handleFileContent(w http.ResponseWriter, r *http.Request) {
+	content, err := s.files.Open(r.PathValue("id"), ownerName(r))
+	if err != nil {
+		writeOpenAIStoreError(w, err)
+		return
+	}
+	defer content.Close()
+
+	w.Header().Set("Content-Type", "application/octet-stream")
+	if _, err := io.Copy(w, content); err != nil {
+		logger.Error("Failed to write file content: %v", err)
+	}
+}
+
+func (s *Server) handleDeleteFile(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	if err := s.files.Delete(id, ownerName(r)); err != nil {
+		writeOpenAIStoreError(w, err)
+		return
+	}
+	writeJSON(w, map[string]any{
+		"id":      id,
+		"object":  "file",
+		"deleted": true,
+	})
+}
@@ -0,0 +1,125 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+
+	"internal/approval"
+	"internal/config"
+	"internal/rate"
+)
+
+const internalRequestContextKey contextKey = "internal-request"
+
+// responseRecorder captures a handler response for in-process requests.
+type responseRecorder struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+func newResponseRecorder() *responseRecorder {
+	return &responseRecorder{header: make(http.Header), status: http.StatusOK}
+}
+
+func (r *responseRecorder) Header() http.Header {
+	return r.header
+}
+
+func (r *responseRecorder) Write(data []byte) (int, error) {
+	return r.body.Write(data)
+}
+
+func (r *responseRecorder) WriteHeader(status int) {
+	r.status = status
+}
+
+// dispatchInternal runs handler in-process on behalf of the key named owner,
+// as if the request had arrived over HTTP, including its usage ledger entry.
+// Streaming is always disabled. Requests whose owner key is no longer
+// configured fail authentication.
+func (s *Server) dispatchInternal(ctx context.Context, handler http.HandlerFunc, path, owner string, body json.RawMessage) (int, json.RawMessage) {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(body, &fields); err == nil {
+		if _, ok := fields["stream"]; ok {
+			fields["stream"] = json.RawMessage("false")
+			if data, err := json.Marshal(fields); err == nil {
+				body = data
+			}
+		}
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, path, bytes.NewReader(body))
+	if err != nil {
+		data, _ := json.Marshal(errorResponse{Error: map[string]any{"message": err.Error(), "type": "error"}})
+		return http.StatusInternalServerError, data
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	rec := newResponseRecorder()
+	ctx = context.WithValue(req.Context(), internalRequestContextKey, true)
+	if owner != "" {
+		key := s.apiKeyByName(owner)
+		if key == nil {
+			writeAuthenticationError(rec, path, fmt.Sprintf("API key %q is no longer configured", owner))
+			return rec.status, bytes.TrimSpace(rec.body.Bytes())
+		}
+		ctx = context.WithValue(ctx, apiKeyContextKey, key)
+	}
+
+	s.recordUsage(handler).ServeHTTP(rec, req.WithContext(ctx))
+	return rec.status, bytes.TrimSpace(rec.body.Bytes())
+}
+
+func isInternalRequest(r *http.Request) bool {
+	internal, _ := r.Context().Value(internalRequestContextKey).(bool)
+	return internal
+}
+
+// apiKeyByName resolves a key recorded by name, e.g. the owner of a batch.
+func (s *Server) apiKeyByName(name string) *config.APIKey {
+	if name == "" {
+		return nil
+	}
+	if name == "default" {
+		if envKey := strings.TrimSpace(os.Getenv("API_KEY")); envKey != "" {
+			return &config.APIKey{Name: "default", Key: envKey}
+		}
+	}
+	for _, key := range s.config().APIKeys {
+		if key.Name == name {
+			k := key
+			return &k
+		}
+	}
+	return nil
+}
+
+func ownerName(r *http.Request) string {
+	if key := apiKeyFromContext(r.Context()); key != nil {
+		return key.Name
+	}
+	return ""
+}
+
+// checkRateLimit applies the rate limit; in-process batch requests always wait.
+func (s *Server) checkRateLimit(r *http.Request) error {
+	if isInternalRequest(r) {
+		return rate.AwaitRateLimit(r.Context(), s.state)
+	}
+	return rate.CheckRateLimit(s.state)
+}
+
+// awaitApproval prompts for manual approval; batch requests are approved once
+// when the batch is created.
+func (s *Server) awaitApproval(r *http.Request) error {
+	if isInternalRequest(r) || !s.manualApprove() {
+		return nil
+	}
+	return approval.AwaitApproval()
+}
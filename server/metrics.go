@@ -0,0 +1,53 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+)
+
+// metrics holds the proxy's counters, exposed in Prometheus text format on
+// /metrics.
+type metrics struct {
+	mu        sync.Mutex
+	fallbacks map[[2]string]int64
+}
+
+func newMetrics() *metrics {
+	return &metrics{fallbacks: make(map[[2]string]int64)}
+}
+
+// fallback counts a request moving from one model to the next in its chain.
+func (m *metrics) fallback(from, to string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.fallbacks[[2]string{from, to}]++
+}
+
+func (s *Server) handleMetrics(w http.ResponseWriter, r *http.Request) {
+	s.metrics.mu.Lock()
+	keys := make([][2]string, 0, len(s.metrics.fallbacks))
+	for key := range s.metrics.fallbacks {
+		keys = append(keys, key)
+	}
+	counts := make(map[[2]string]int64, len(keys))
+	for _, key := range keys {
+		counts[key] = s.metrics.fallbacks[key]
+	}
+	s.metrics.mu.Unlock()
+
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i][0] != keys[j][0] {
+			return keys[i][0] < keys[j][0]
+		}
+		return keys[i][1] < keys[j][1]
+	})
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	fmt.Fprintln(w, "# HELP copilot_api_model_fallbacks_total Requests retried on the next model of a fallback chain.")
+	fmt.Fprintln(w, "# TYPE copilot_api_model_fallbacks_total counter")
+	for _, key := range keys {
+		fmt.Fprintf(w, "copilot_api_model_fallbacks_total{from=%q,to=%q} %d\n", key[0], key[1], counts[key])
+	}
+}
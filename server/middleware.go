@@ -1,11 +1,13 @@
 package server
 
 import (
+	"context"
 	"net/http"
 	"os"
 	"strings"
 	"time"
 
+	"internal/config"
 	"internal/logger"
 )
 
@@ -46,8 +48,8 @@ func (w *statusWriter) Flush() {
 func CORSMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Access-Control-Allow-Origin", "*")
-		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, OPTIONS")
-		w.Header().Set("Access-Control-Allow-Headers", "Authorization, Content-Type, x-api-key")
+		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, DELETE, OPTIONS")
+		w.Header().Set("Access-Control-Allow-Headers", "Authorization, Content-Type, x-api-key, X-Copilot-Compaction, X-Copilot-Conversation")
 
 		if r.Method == http.MethodOptions {
 			w.WriteHeader(http.StatusNoContent)
@@ -58,36 +60,63 @@ func CORSMiddleware(next http.Handler) http.Handler {
 	})
 }
 
-func APIKeyMiddleware(next http.Handler) http.Handler {
-	apiKey := strings.TrimSpace(os.Getenv("API_KEY"))
-	if apiKey == "" {
-		return next
-	}
+type contextKey string
+
+const apiKeyContextKey contextKey = "api-key"
 
+// APIKeyMiddleware authenticates requests against API_KEY and the keys listed
+// in config.json. The matched key is stored on the request context.
+func (s *Server) APIKeyMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		authenticated := false
+		envKey := strings.TrimSpace(os.Getenv("API_KEY"))
+		cfg := s.config()
+		if envKey == "" && len(cfg.APIKeys) == 0 {
+			next.ServeHTTP(w, r)
+			return
+		}
 
+		var candidates []string
 		if header := r.Header.Get("Authorization"); header != "" {
 			if strings.HasPrefix(strings.ToLower(header), "bearer ") {
-				token := strings.TrimSpace(header[7:])
-				if token == apiKey {
-					authenticated = true
-				}
+				candidates = append(candidates, strings.TrimSpace(header[7:]))
 			}
 		}
+		if xKey := r.Header.Get("x-api-key"); xKey != "" {
+			candidates = append(candidates, xKey)
+		}
 
-		if !authenticated {
-			if xKey := r.Header.Get("x-api-key"); xKey == apiKey {
-				authenticated = true
+		var matched *config.APIKey
+		for _, candidate := range candidates {
+			if envKey != "" && candidate == envKey {
+				matched = &config.APIKey{Name: "default", Key: envKey}
+				break
+			}
+			if key, ok := cfg.FindAPIKey(candidate); ok {
+				matched = key
+				break
 			}
 		}
 
-		if !authenticated {
-			w.WriteHeader(http.StatusUnauthorized)
-			_, _ = w.Write([]byte(`{"error":"Unauthorized: Invalid or missing API key"}`))
+		if matched == nil {
+			writeAuthenticationError(w, r.URL.Path, "Invalid or missing API key")
 			return
 		}
 
-		next.ServeHTTP(w, r)
+		ctx := context.WithValue(r.Context(), apiKeyContextKey, matched)
+		next.ServeHTTP(w, r.WithContext(ctx))
 	})
 }
+
+// isEnvKey reports whether r authenticated with the API_KEY environment
+// variable, the operator key that may see every key's data.
+func isEnvKey(r *http.Request) bool {
+	key := apiKeyFromContext(r.Context())
+	envKey := strings.TrimSpace(os.Getenv("API_KEY"))
+	return key != nil && envKey != "" && key.Key == envKey
+}
+
+// apiKeyFromContext returns the authenticated key, or nil when auth is disabled.
+func apiKeyFromContext(ctx context.Context) *config.APIKey {
+	key, _ := ctx.Value(apiKeyContextKey).(*config.APIKey)
+	return key
+}
@@ -0,0 +1,68 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+
+	"internal/config"
+	appErr "internal/errors"
+	"internal/logger"
+	"internal/usage"
+)
+
+// applyQuotaPolicy reports the cached premium quota in response headers and,
+// once it is at or below the configured minimum, refuses premium models or
+// returns the substitute to use instead. Nothing is done until upstream has
+// reported a premium quota.
+func (s *Server) applyQuotaPolicy(w http.ResponseWriter, model string) (string, error) {
+	cached := usage.Cached(s.state)
+	if cached == nil || cached.QuotaSnapshots.PremiumInteractions == nil {
+		return model, nil
+	}
+	premium := cached.QuotaSnapshots.PremiumInteractions
+	if premium.Unlimited {
+		w.Header().Set("X-Copilot-Premium-Remaining", "unlimited")
+		return model, nil
+	}
+	w.Header().Set("X-Copilot-Premium-Remaining", strconv.FormatFloat(premium.Remaining, 'f', -1, 64))
+	w.Header().Set("X-Copilot-Premium-Entitlement", strconv.FormatFloat(premium.Entitlement, 'f', -1, 64))
+	if cached.QuotaResetDate != "" {
+		w.Header().Set("X-Copilot-Quota-Reset", cached.QuotaResetDate)
+	}
+
+	cfg := s.config().Quota
+	if cfg.Action == "" || premium.Remaining > cfg.MinPremiumRemaining || !s.isPremium(model) {
+		return model, nil
+	}
+
+	if cfg.Action == config.QuotaDowngrade {
+		substitute := cfg.Substitutes[model]
+		if substitute == "" {
+			substitute = cfg.DefaultSubstitute
+		}
+		if substitute != "" && substitute != model {
+			logger.Info("Premium quota low (%.0f remaining), using %s instead of %s", premium.Remaining, substitute, model)
+			w.Header().Set("X-Copilot-Quota-Downgraded-From", model)
+			return substitute, nil
+		}
+	}
+
+	message := fmt.Sprintf("Premium request quota is low (%s remaining); %s is not available until the quota resets.",
+		strconv.FormatFloat(premium.Remaining, 'f', -1, 64), model)
+	resp := appErr.NewJSONResponse(http.StatusTooManyRequests, map[string]any{
+		"error": map[string]any{"message": message, "code": "quota_exceeded"},
+	})
+	return model, appErr.NewHTTPError("Premium quota exhausted", resp)
+}
+
+// isPremium reports whether requests for model count against the premium
+// quota, per the model list or the configured premium_models.
+func (s *Server) isPremium(model string) bool {
+	if slices.Contains(s.config().Quota.PremiumModels, model) {
+		return true
+	}
+	info, ok := s.findModel(model)
+	return ok && info.IsPremium()
+}
@@ -0,0 +1,306 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"internal/logger"
+	"internal/responses"
+	"internal/services/copilot"
+)
+
+// responsesSession records what to persist once a Responses request
+// completes.
+type responsesSession struct {
+	owner        string
+	previousID   string
+	conversation string
+	store        bool
+	input        []json.RawMessage
+}
+
+// EnableResponseStore keeps responses in dir so previous_response_id,
+// retrieve, delete and input_items work without upstream storage.
+func (s *Server) EnableResponseStore(dir string) error {
+	cfg := s.config().ResponseStore
+	store, err := responses.NewStore(dir, responses.StoreOptions{
+		TTL:        time.Duration(cfg.TTLHours) * time.Hour,
+		MaxEntries: cfg.MaxEntries,
+		MaxBytes:   cfg.MaxBytes,
+	})
+	if err != nil {
+		return err
+	}
+	s.responseStore = store
+
+	for _, prefix := range []string{"", "/v1"} {
+		s.mux.Handle("GET "+prefix+"/responses/{id}", Chain(http.HandlerFunc(s.handleGetResponse), s.APIKeyMiddleware))
+		s.mux.Handle("DELETE "+prefix+"/responses/{id}", Chain(http.HandlerFunc(s.handleDeleteResponse), s.APIKeyMiddleware))
+		s.mux.Handle("GET "+prefix+"/responses/{id}/input_items", Chain(http.HandlerFunc(s.handleResponseInputItems), s.APIKeyMiddleware))
+	}
+	return nil
+}
+
+// previousResponseError reports a previous_response_id that is unknown or
+// has expired.
+type previousResponseError struct {
+	id string
+}
+
+func (e *previousResponseError) Error() string {
+	return "Previous response with id '" + e.id + "' not found."
+}
+
+// restoreResponsesState expands previous_response_id or a conversation into
+// the full input and removes the fields upstream cannot honour. The returned
+// session is nil when neither store is enabled.
+func (s *Server) restoreResponsesState(r *http.Request, rawBody []byte) ([]byte, *responsesSession, error) {
+	if s.responseStore == nil && s.conversations == nil {
+		return rawBody, nil, nil
+	}
+
+	var body map[string]json.RawMessage
+	if err := json.Unmarshal(rawBody, &body); err != nil {
+		return nil, nil, err
+	}
+
+	session := &responsesSession{owner: ownerName(r), store: string(body["store"]) != "false"}
+	input, err := responses.NormalizeInput(body["input"])
+	if err != nil {
+		return nil, nil, err
+	}
+	session.input = input
+
+	if raw, ok := body["previous_response_id"]; ok {
+		json.Unmarshal(raw, &session.previousID)
+		delete(body, "previous_response_id")
+	}
+	if raw, ok := body["conversation"]; ok {
+		session.conversation = conversationID(raw)
+		delete(body, "conversation")
+	}
+	if session.conversation == "" {
+		session.conversation = r.Header.Get(conversationHeader)
+	}
+
+	var items []json.RawMessage
+	switch {
+	case session.previousID != "" && session.conversation != "":
+		return nil, nil, errors.New("previous_response_id and conversation cannot be used together")
+	case session.previousID != "" && s.responseStore != nil:
+		items, err = s.responseStore.Conversation(session.previousID, session.owner)
+		if err != nil {
+			if errors.Is(err, responses.ErrResponseNotFound) {
+				return nil, nil, &previousResponseError{id: session.previousID}
+			}
+			return nil, nil, err
+		}
+		logger.Debug("Expanded previous_response_id %s into %d input items", session.previousID, len(items))
+	case session.conversation != "" && s.conversations != nil:
+		stored, err := s.conversations.Items(session.conversation, session.owner)
+		if err != nil {
+			return nil, nil, err
+		}
+		items = responses.ReplayItems(stored)
+		logger.Debug("Expanded conversation %s into %d input items", session.conversation, len(items))
+	default:
+		return rawBody, session, nil
+	}
+
+	for _, item := range input {
+		items = append(items, responses.StripItemID(item))
+	}
+	expanded, err := json.Marshal(items)
+	if err != nil {
+		return nil, nil, err
+	}
+	body["input"] = expanded
+
+	rewritten, err := json.Marshal(body)
+	if err != nil {
+		return nil, nil, err
+	}
+	return rewritten, session, nil
+}
+
+// storeResponse appends a finished response to the request's conversation
+// and saves it unless the request opted out with store: false.
+func (s *Server) storeResponse(session *responsesSession, response any) {
+	if session == nil {
+		return
+	}
+
+	data, err := json.Marshal(response)
+	if err != nil {
+		logger.Warn("Failed to encode response for storage: %v", err)
+		return
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return
+	}
+	var id string
+	json.Unmarshal(fields["id"], &id)
+	if id == "" {
+		return
+	}
+	if session.previousID != "" || session.conversation != "" {
+		if session.previousID != "" {
+			fields["previous_response_id"], _ = json.Marshal(session.previousID)
+		}
+		if session.conversation != "" {
+			fields["conversation"], _ = json.Marshal(responses.ConversationRef{ID: session.conversation})
+		}
+		data, _ = json.Marshal(fields)
+	}
+
+	if session.conversation != "" && s.conversations != nil {
+		var output []json.RawMessage
+		json.Unmarshal(fields["output"], &output)
+		items := append(append([]json.RawMessage{}, session.input...), output...)
+		if err := s.conversations.Append(session.conversation, session.owner, items); err != nil {
+			logger.Warn("Failed to append to conversation %s: %v", session.conversation, err)
+		}
+	}
+	if !session.store || s.responseStore == nil {
+		return
+	}
+
+	if err := s.responseStore.Save(responses.StoredResponse{
+		ID:                 id,
+		PreviousResponseID: session.previousID,
+		Input:              session.input,
+		Response:           data,
+		Owner:              session.owner,
+	}); err != nil {
+		logger.Warn("Failed to store response %s: %v", id, err)
+		return
+	}
+	logger.Debug("Stored response %s", id)
+}
+
+// recordResponsesStream forwards an upstream Responses stream unchanged and
+// stores the response carried by its terminal event.
+func (s *Server) recordResponsesStream(ctx context.Context, upstream copilot.ResponsesStream, session *responsesSession) copilot.ResponsesStream {
+	out := make(chan copilot.SSEMessage)
+	go func() {
+		defer close(out)
+		for msg := range upstream {
+			var event struct {
+				Type     string          `json:"type"`
+				Response json.RawMessage `json:"response"`
+			}
+			if json.Unmarshal([]byte(msg.Data), &event) == nil && len(event.Response) > 0 {
+				switch event.Type {
+				case "response.completed", "response.incomplete":
+					s.storeResponse(session, event.Response)
+				}
+			}
+			select {
+			case out <- msg:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return out
+}
+
+func (s *Server) handleGetResponse(w http.ResponseWriter, r *http.Request) {
+	stored, err := s.responseStore.Get(r.PathValue("id"), ownerName(r))
+	if err != nil {
+		writeResponseStoreError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(stored.Response)
+}
+
+func (s *Server) handleDeleteResponse(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	if err := s.responseStore.Delete(id, ownerName(r)); err != nil {
+		writeResponseStoreError(w, err)
+		return
+	}
+	writeJSON(w, map[string]any{"id": id, "object": "response", "deleted": true})
+}
+
+func (s *Server) handleResponseInputItems(w http.ResponseWriter, r *http.Request) {
+	stored, err := s.responseStore.Get(r.PathValue("id"), ownerName(r))
+	if err != nil {
+		writeResponseStoreError(w, err)
+		return
+	}
+
+	page, hasMore, ok := pageItems(w, r, stored.Input)
+	if !ok {
+		return
+	}
+	writeJSON(w, openAIList(page, hasMore, responses.ItemID))
+}
+
+// pageItems applies the limit, order (default desc) and after query
+// parameters of the item list endpoints.
+func pageItems(w http.ResponseWriter, r *http.Request, items []json.RawMessage) ([]json.RawMessage, bool, bool) {
+	limit, ok := parseOpenAILimit(w, r, 20, 100)
+	if !ok {
+		return nil, false, false
+	}
+	query := r.URL.Query()
+
+	items = append([]json.RawMessage{}, items...)
+	switch query.Get("order") {
+	case "", "desc":
+		for i, j := 0, len(items)-1; i < j; i, j = i+1, j-1 {
+			items[i], items[j] = items[j], items[i]
+		}
+	case "asc":
+	default:
+		writeOpenAIError(w, http.StatusBadRequest, "invalid_request_error", "order: must be one of asc, desc")
+		return nil, false, false
+	}
+
+	if after := query.Get("after"); after != "" {
+		for i, item := range items {
+			if responses.ItemID(item) == after {
+				items = items[i+1:]
+				break
+			}
+		}
+	}
+	if len(items) > limit {
+		return items[:limit], true, true
+	}
+	return items, false, true
+}
+
+func writeResponseStoreError(w http.ResponseWriter, err error) {
+	var previous *previousResponseError
+	switch {
+	case errors.As(err, &previous):
+		writeOpenAIError(w, http.StatusBadRequest, "invalid_request_error", previous.Error())
+	case errors.Is(err, responses.ErrResponseNotFound):
+		writeOpenAIError(w, http.StatusNotFound, "invalid_request_error", "Response not found.")
+	case errors.Is(err, responses.ErrConversationNotFound):
+		writeOpenAIError(w, http.StatusNotFound, "invalid_request_error", err.Error())
+	default:
+		writeOpenAIError(w, http.StatusBadRequest, "invalid_request_error", err.Error())
+	}
+}
+
+// conversationID accepts the conversation parameter as either an ID or an
+// object with an id field.
+func conversationID(raw json.RawMessage) string {
+	var id string
+	if err := json.Unmarshal(raw, &id); err == nil {
+		return id
+	}
+	var ref struct {
+		ID string `json:"id"`
+	}
+	json.Unmarshal(raw, &ref)
+	return ref.ID
+}
@@ -0,0 +1,243 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	appErr "internal/errors"
+	"internal/logger"
+	"internal/responses"
+	"internal/services/copilot"
+)
+
+// handleResponsesViaChat serves a Responses API request for a model that is
+// only reachable through chat completions.
+func (s *Server) handleResponsesViaChat(w http.ResponseWriter, r *http.Request, rawBody []byte, session *responsesSession) {
+	var req responses.Request
+	if err := json.Unmarshal(rawBody, &req); err != nil {
+		writeOpenAIError(w, http.StatusBadRequest, "invalid_request_error", err.Error())
+		return
+	}
+	if session != nil && session.previousID != "" {
+		req.PreviousResponseID = &session.previousID
+	}
+
+	payload, err := responses.TranslateToChat(req)
+	if err != nil {
+		writeOpenAIError(w, http.StatusBadRequest, "invalid_request_error", err.Error())
+		return
+	}
+	logger.Debug("Serving responses request for model %s via chat completions", req.Model)
+
+	payload, err = s.compact(w, r, payload)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	payload, structured, err := s.prepareStructuredOutput(payload)
+	if err != nil {
+		writeOpenAIError(w, http.StatusBadRequest, "invalid_request_error", err.Error())
+		return
+	}
+
+	stream := payload.Stream != nil && *payload.Stream
+	result, err := s.withFallback(w, r, s.fallbackChain(payload.Model, ""), payload.Messages, func(ctx context.Context, model string) (interface{}, error) {
+		payload.Model = model
+		if structured != nil && !stream {
+			return s.createStructuredCompletion(ctx, w, payload, structured)
+		}
+		return s.createChatCompletions(ctx, payload)
+	})
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	if stream {
+		s.forwardResponsesStream(w, r, req, result, session)
+		return
+	}
+
+	completion, ok := result.(copilot.ChatCompletionResponse)
+	if !ok {
+		writeError(w, fmt.Errorf("unexpected response type"))
+		return
+	}
+	response := responses.TranslateFromChat(completion, req)
+	if session != nil && session.conversation != "" {
+		response.Conversation = &responses.ConversationRef{ID: session.conversation}
+	}
+	s.storeResponse(session, response)
+	writeJSON(w, response)
+}
+
+func (s *Server) forwardResponsesStream(w http.ResponseWriter, r *http.Request, req responses.Request, result interface{}, session *responsesSession) {
+	ch, ok := result.(<-chan copilot.SSEMessage)
+	if !ok {
+		writeError(w, fmt.Errorf("invalid stream type"))
+		return
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeError(w, fmt.Errorf("streaming unsupported by server"))
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+
+	write := func(events []responses.SSEEvent) {
+		for _, event := range events {
+			fmt.Fprintf(w, "event: %s\n", event.Type)
+			fmt.Fprintf(w, "data: %s\n\n", event.Data)
+		}
+		flusher.Flush()
+	}
+
+	ctx := r.Context()
+	streamState := responses.NewStreamState(req)
+	timers := s.newStreamTimers()
+	defer timers.stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-timers.heartbeatC():
+			writeHeartbeat(w, dialectResponses)
+			flusher.Flush()
+			timers.wrote()
+		case <-timers.idleC():
+			writeStreamError(w, dialectResponses, timers.idleError())
+			flusher.Flush()
+			return
+		case msg, ok := <-ch:
+			timers.received()
+			if ok && msg.Err != nil {
+				writeStreamError(w, dialectResponses, msg.Err)
+				flusher.Flush()
+				return
+			}
+			if !ok || msg.Data == "[DONE]" {
+				events, err := streamState.Finish()
+				if err != nil {
+					writeStreamError(w, dialectResponses, fmt.Errorf("failed to finish stream: %w", err))
+					flusher.Flush()
+					return
+				}
+				write(events)
+				s.storeResponse(session, streamState.Response())
+				return
+			}
+			if msg.Data == "" {
+				continue
+			}
+			if failure := appErr.ParseStreamError([]byte(msg.Data)); failure != nil {
+				writeStreamError(w, dialectResponses, failure)
+				flusher.Flush()
+				return
+			}
+
+			var chunk copilot.ChatCompletionChunk
+			if err := json.Unmarshal([]byte(msg.Data), &chunk); err != nil {
+				writeStreamError(w, dialectResponses, fmt.Errorf("invalid stream chunk: %w", err))
+				flusher.Flush()
+				return
+			}
+			events, err := streamState.TranslateChunk(chunk)
+			if err != nil {
+				writeStreamError(w, dialectResponses, fmt.Errorf("failed to translate stream chunk: %w", err))
+				flusher.Flush()
+				return
+			}
+			write(events)
+		}
+	}
+}
+
+const thinkingContextKey contextKey = "thinking"
+
+// withThinking marks r as asking for reasoning output, e.g. an Anthropic
+// request with thinking enabled, which has no chat completions equivalent.
+func withThinking(r *http.Request) *http.Request {
+	return r.WithContext(context.WithValue(r.Context(), thinkingContextKey, true))
+}
+
+// createChatViaResponses serves a chat completion from a /responses-only
+// model, returning the same result types as copilot.CreateChatCompletions.
+func (s *Server) createChatViaResponses(ctx context.Context, payload copilot.ChatCompletionsPayload) (interface{}, error) {
+	req, err := responses.RequestFromChat(payload)
+	if err != nil {
+		return nil, err
+	}
+	if thinking, _ := ctx.Value(thinkingContextKey).(bool); thinking && req.Reasoning == nil {
+		summary := "auto"
+		req.Reasoning = &responses.Reasoning{Summary: &summary}
+	}
+	body, err := json.Marshal(req)
+	if err != nil {
+		return nil, err
+	}
+	logger.Debug("Serving chat completion for model %s via responses", payload.Model)
+
+	stream := payload.Stream != nil && *payload.Stream
+	result, err := s.createResponses(ctx, payload.Model, body, copilot.ResponsesRequestOptions{
+		Vision:    payload.ContainsVision(),
+		Initiator: copilot.ResolveChatInitiator(payload.Model, payload.Messages),
+		Stream:    stream,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	switch typed := result.(type) {
+	case copilot.ResponsesStream:
+		return responses.ToChatStream(ctx, typed), nil
+	case copilot.ResponsesResult:
+		data, err := json.Marshal(typed)
+		if err != nil {
+			return nil, err
+		}
+		var response responses.Response
+		if err := json.Unmarshal(data, &response); err != nil {
+			return nil, err
+		}
+		return responses.CompletionFromResponse(response), nil
+	default:
+		return nil, fmt.Errorf("unexpected response type")
+	}
+}
+
+// createResponses calls the upstream /responses endpoint, streaming and
+// reassembling non-streaming requests when the upstream config forces
+// streaming.
+func (s *Server) createResponses(ctx context.Context, model string, body []byte, opts copilot.ResponsesRequestOptions) (interface{}, error) {
+	if opts.Stream || !s.config().Upstream.ForcesStream(model) {
+		return copilot.CreateResponses(ctx, s.state, body, opts, s.client, s.streamer)
+	}
+
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(body, &fields); err != nil {
+		return nil, err
+	}
+	fields["stream"] = json.RawMessage("true")
+	streamed, err := json.Marshal(fields)
+	if err != nil {
+		return nil, err
+	}
+	logger.Debug("Forcing upstream streaming for model %s", model)
+
+	opts.Stream = true
+	result, err := copilot.CreateResponses(ctx, s.state, streamed, opts, s.client, s.streamer)
+	if err != nil {
+		return nil, err
+	}
+	stream, ok := result.(copilot.ResponsesStream)
+	if !ok {
+		return result, nil
+	}
+	return copilot.AggregateResponsesStream(ctx, stream)
+}
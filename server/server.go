@@ -1,27 +1,42 @@
 package server
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"time"
 
-	"internal/approval"
+	"internal/batches"
+	"internal/budget"
+	"internal/config"
+	appErr "internal/errors"
 	"internal/logger"
 	"internal/messages"
-	"internal/rate"
+	"internal/responses"
 	"internal/services/copilot"
 	"internal/services/github"
 	"internal/state"
 	"internal/streaming"
+	"internal/usage"
 )
 
 type Server struct {
-	state    *state.State
-	client   *http.Client
-	streamer copilot.SSEReader
-	mux      *http.ServeMux
+	state          *state.State
+	client         *http.Client
+	streamer       copilot.SSEReader
+	mux            *http.ServeMux
+	messageBatches *batches.MessageBatches
+	openaiBatches  *batches.OpenAIBatches
+	files          *batches.Files
+	responseStore  *responses.Store
+	conversations  *responses.Conversations
+	metrics        *metrics
+	budgets        *budget.Ledger
+	usageLedger    *usage.Ledger
+	usageHistory   *usage.History
 }
 
 func New(s *state.State, client *http.Client) *Server {
@@ -30,11 +45,12 @@ func New(s *state.State, client *http.Client) *Server {
 	}
 
 	srv := &Server{
-		state:    s,
-		client:   client,
-		streamer: streaming.Reader{},
-		mux:      http.NewServeMux(),
+		state:   s,
+		client:  client,
+		mux:     http.NewServeMux(),
+		metrics: newMetrics(),
 	}
+	srv.streamer = streaming.Reader{MaxLineBytes: srv.config().Upstream.MaxSSELineBytes}
 
 	srv.routes()
 	return srv
@@ -46,22 +62,54 @@ func (s *Server) Handler() http.Handler {
 
 func (s *Server) routes() {
 	s.mux.HandleFunc("/", s.handleRoot)
-	s.mux.Handle("/chat/completions", Chain(http.HandlerFunc(s.handleChatCompletions), APIKeyMiddleware))
-	s.mux.Handle("/v1/chat/completions", Chain(http.HandlerFunc(s.handleChatCompletions), APIKeyMiddleware))
+	s.mux.Handle("/chat/completions", Chain(http.HandlerFunc(s.handleChatCompletions), s.APIKeyMiddleware, s.recordUsage))
+	s.mux.Handle("/v1/chat/completions", Chain(http.HandlerFunc(s.handleChatCompletions), s.APIKeyMiddleware, s.recordUsage))
 
-	s.mux.Handle("/embeddings", Chain(http.HandlerFunc(s.handleEmbeddings), APIKeyMiddleware))
-	s.mux.Handle("/v1/embeddings", Chain(http.HandlerFunc(s.handleEmbeddings), APIKeyMiddleware))
+	s.mux.Handle("/embeddings", Chain(http.HandlerFunc(s.handleEmbeddings), s.APIKeyMiddleware, s.recordUsage))
+	s.mux.Handle("/v1/embeddings", Chain(http.HandlerFunc(s.handleEmbeddings), s.APIKeyMiddleware, s.recordUsage))
 
 	s.mux.Handle("/models", http.HandlerFunc(s.handleModels))
 	s.mux.Handle("/v1/models", http.HandlerFunc(s.handleModels))
 
 	s.mux.Handle("/usage", http.HandlerFunc(s.handleUsage))
+	s.mux.Handle("/metrics", Chain(http.HandlerFunc(s.handleMetrics), s.APIKeyMiddleware))
 
-	s.mux.Handle("/responses", Chain(http.HandlerFunc(s.handleResponses), APIKeyMiddleware))
-	s.mux.Handle("/v1/responses", Chain(http.HandlerFunc(s.handleResponses), APIKeyMiddleware))
+	s.mux.Handle("/responses", Chain(http.HandlerFunc(s.handleResponses), s.APIKeyMiddleware, s.recordUsage))
+	s.mux.Handle("/v1/responses", Chain(http.HandlerFunc(s.handleResponses), s.APIKeyMiddleware, s.recordUsage))
 
-	s.mux.Handle("/v1/messages", Chain(http.HandlerFunc(s.handleMessages), APIKeyMiddleware))
-	s.mux.Handle("/v1/messages/count_tokens", Chain(http.HandlerFunc(s.handleMessagesCountTokens), APIKeyMiddleware))
+	s.mux.Handle("/v1/messages", Chain(http.HandlerFunc(s.handleMessages), s.APIKeyMiddleware, s.recordUsage))
+	s.mux.Handle("/v1/messages/count_tokens", Chain(http.HandlerFunc(s.handleMessagesCountTokens), s.APIKeyMiddleware))
+}
+
+// config returns the loaded config.json, or the defaults before it is loaded.
+func (s *Server) config() *config.Config {
+	var cfg *config.Config
+	s.state.Read(func(st *state.State) {
+		cfg = st.Config
+	})
+	if cfg == nil {
+		return config.Default()
+	}
+	return cfg
+}
+
+// findModel looks up a model in the cached model list.
+func (s *Server) findModel(id string) (*copilot.Model, bool) {
+	var models *copilot.ModelsResponse
+	s.state.Read(func(st *state.State) {
+		if cached, ok := st.Models.(*copilot.ModelsResponse); ok {
+			models = cached
+		}
+	})
+	if models == nil {
+		return nil, false
+	}
+	for i := range models.Data {
+		if models.Data[i].ID == id {
+			return &models.Data[i], true
+		}
+	}
+	return nil, false
 }
 
 func (s *Server) handleRoot(w http.ResponseWriter, r *http.Request) {
@@ -80,7 +128,7 @@ func (s *Server) handleRoot(w http.ResponseWriter, r *http.Request) {
 }
 
 func (s *Server) handleChatCompletions(w http.ResponseWriter, r *http.Request) {
-	if err := rate.CheckRateLimit(s.state); err != nil {
+	if err := s.checkRateLimit(r); err != nil {
 		writeError(w, err)
 		return
 	}
@@ -94,15 +142,31 @@ func (s *Server) handleChatCompletions(w http.ResponseWriter, r *http.Request) {
 
 	var payload copilot.ChatCompletionsPayload
 	if err := json.Unmarshal(body, &payload); err != nil {
+		writeOpenAIError(w, http.StatusBadRequest, "invalid_request_error", err.Error())
+		return
+	}
+
+	if err := s.awaitApproval(r); err != nil {
 		writeError(w, err)
 		return
 	}
 
-	if manual := s.manualApprove(); manual {
-		if err := approval.AwaitApproval(); err != nil {
-			writeError(w, err)
-			return
-		}
+	payload, turn, err := s.expandConversation(r, payload)
+	if err != nil {
+		writeConversationError(w, err)
+		return
+	}
+
+	payload, err = s.compact(w, r, payload)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	payload, structured, err := s.prepareStructuredOutput(payload)
+	if err != nil {
+		writeOpenAIError(w, http.StatusBadRequest, "invalid_request_error", err.Error())
+		return
 	}
 
 	stream := payload.Stream != nil && *payload.Stream
@@ -112,22 +176,120 @@ func (s *Server) handleChatCompletions(w http.ResponseWriter, r *http.Request) {
 		logger.Debug("Non-streaming chat completion for model %s", payload.Model)
 	}
 
-	result, err := copilot.CreateChatCompletions(r.Context(), s.state, payload, s.client, s.streamer)
+	result, err := s.withFallback(w, r, s.fallbackChain(payload.Model, ""), payload.Messages, func(ctx context.Context, model string) (interface{}, error) {
+		payload.Model = model
+		if structured != nil && !stream {
+			return s.createStructuredCompletion(ctx, w, payload, structured)
+		}
+		return s.createChatCompletions(ctx, payload)
+	})
 	if err != nil {
 		writeError(w, err)
 		return
 	}
 
 	if stream {
-		s.forwardStream(w, r, result)
+		s.forwardStream(w, r, s.recordConversationStream(r.Context(), result, turn))
 		return
 	}
+	s.appendConversationCompletion(turn, result)
 
 	logger.Debug("Chat completion response ready (non-streaming)")
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(result)
 }
 
+// createChatCompletions sends payload upstream, fanning out when the model
+// cannot produce n choices itself and going through /responses when the model
+// has no chat completions endpoint. Non-streaming requests are streamed and
+// reassembled when the upstream config forces streaming.
+func (s *Server) createChatCompletions(ctx context.Context, payload copilot.ChatCompletionsPayload) (interface{}, error) {
+	stream := payload.Stream != nil && *payload.Stream
+	switch {
+	case stream && !s.supportsStreaming(payload.Model):
+		return s.createSynthesizedStream(ctx, payload)
+	case !stream && s.supportsStreaming(payload.Model) && s.config().Upstream.ForcesStream(payload.Model):
+		return s.createAggregatedChatCompletion(ctx, payload)
+	}
+	return s.sendChatCompletions(ctx, payload)
+}
+
+// supportsStreaming reports whether the model can stream; models without
+// capability data are assumed to.
+func (s *Server) supportsStreaming(id string) bool {
+	model, ok := s.findModel(id)
+	if !ok || model.Capabilities.Supports.Streaming == nil {
+		return true
+	}
+	return *model.Capabilities.Supports.Streaming
+}
+
+// createSynthesizedStream serves a streaming request for a model that cannot
+// stream by replaying a non-streaming completion as chunks.
+func (s *Server) createSynthesizedStream(ctx context.Context, payload copilot.ChatCompletionsPayload) (interface{}, error) {
+	payload.Stream = nil
+	payload.StreamOptions = nil
+	logger.Debug("Model %s does not stream; synthesizing the stream", payload.Model)
+
+	result, err := s.sendChatCompletions(ctx, payload)
+	if err != nil {
+		return nil, err
+	}
+	completion, ok := result.(copilot.ChatCompletionResponse)
+	if !ok {
+		return result, nil
+	}
+	return copilot.SynthesizeStream(ctx, completion), nil
+}
+
+// createAggregatedChatCompletion streams a non-streaming request upstream and
+// returns the reassembled ChatCompletionResponse.
+func (s *Server) createAggregatedChatCompletion(ctx context.Context, payload copilot.ChatCompletionsPayload) (interface{}, error) {
+	stream, includeUsage := true, true
+	payload.Stream = &stream
+	if payload.StreamOptions == nil {
+		payload.StreamOptions = &copilot.StreamOptions{IncludeUsage: &includeUsage}
+	}
+	logger.Debug("Forcing upstream streaming for model %s", payload.Model)
+
+	result, err := s.sendChatCompletions(ctx, payload)
+	if err != nil {
+		return nil, err
+	}
+	ch, ok := result.(<-chan copilot.SSEMessage)
+	if !ok {
+		return result, nil
+	}
+	return copilot.AggregateStream(ctx, ch)
+}
+
+func (s *Server) sendChatCompletions(ctx context.Context, payload copilot.ChatCompletionsPayload) (interface{}, error) {
+	if model, ok := s.findModel(payload.Model); ok && model.ResponsesOnly() {
+		return s.createChatViaResponses(ctx, payload)
+	}
+	if n := s.fanOutChoices(payload); n > 1 {
+		if err := reserveUpstreamCalls(ctx, n-1); err != nil {
+			return nil, err
+		}
+		logger.Debug("Emulating n=%d for model %s with parallel requests", n, payload.Model)
+		return copilot.CreateChatCompletionsFanOut(ctx, s.state, payload, n, s.client, s.streamer)
+	}
+	return copilot.CreateChatCompletions(ctx, s.state, payload, s.client, s.streamer)
+}
+
+// fanOutChoices returns the number of parallel requests needed to emulate n
+// choices, or 0 when the upstream model handles n itself.
+func (s *Server) fanOutChoices(payload copilot.ChatCompletionsPayload) int {
+	if payload.N == nil || *payload.N <= 1 {
+		return 0
+	}
+	model, ok := s.findModel(payload.Model)
+	if !ok || model.SupportsMultipleChoices() {
+		return 0
+	}
+	return *payload.N
+}
+
 func (s *Server) manualApprove() bool {
 	var manual bool
 	s.state.Read(func(st *state.State) {
@@ -137,8 +299,13 @@ func (s *Server) manualApprove() bool {
 }
 
 func (s *Server) forwardStream(w http.ResponseWriter, r *http.Request, stream interface{}) {
-	messageChan, ok := stream.(<-chan copilot.SSEMessage)
-	if !ok {
+	var messageChan <-chan copilot.SSEMessage
+	switch typed := stream.(type) {
+	case <-chan copilot.SSEMessage:
+		messageChan = typed
+	case copilot.ResponsesStream:
+		messageChan = typed
+	default:
 		writeError(w, fmt.Errorf("invalid stream type"))
 		return
 	}
@@ -152,15 +319,39 @@ func (s *Server) forwardStream(w http.ResponseWriter, r *http.Request, stream in
 	w.Header().Set("Content-Type", "text/event-stream")
 	w.Header().Set("Cache-Control", "no-cache")
 
+	dialect := dialectOpenAI
+	if _, ok := stream.(copilot.ResponsesStream); ok {
+		dialect = dialectResponses
+	}
+	timers := s.newStreamTimers()
+	defer timers.stop()
+
 	ctx := r.Context()
 	for {
 		select {
 		case <-ctx.Done():
 			return
+		case <-timers.heartbeatC():
+			writeHeartbeat(w, dialect)
+			flusher.Flush()
+			timers.wrote()
+		case <-timers.idleC():
+			writeStreamError(w, dialect, timers.idleError())
+			flusher.Flush()
+			return
 		case msg, ok := <-messageChan:
 			if !ok {
 				return
 			}
+			timers.received()
+			if msg.Err != nil {
+				writeStreamError(w, dialect, msg.Err)
+				flusher.Flush()
+				return
+			}
+			if msg.ID != "" {
+				fmt.Fprintf(w, "id: %s\n", msg.ID)
+			}
 			if msg.Event != "" {
 				fmt.Fprintf(w, "event: %s\n", msg.Event)
 			}
@@ -172,29 +363,30 @@ func (s *Server) forwardStream(w http.ResponseWriter, r *http.Request, stream in
 }
 
 func (s *Server) handleEmbeddings(w http.ResponseWriter, r *http.Request) {
-	if err := rate.CheckRateLimit(s.state); err != nil {
+	if err := s.checkRateLimit(r); err != nil {
 		writeError(w, err)
 		return
 	}
 
 	var payload copilot.EmbeddingRequest
 	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
-		writeError(w, err)
+		writeOpenAIError(w, http.StatusBadRequest, "invalid_request_error", err.Error())
 		return
 	}
 
-	if manual := s.manualApprove(); manual {
-		if err := approval.AwaitApproval(); err != nil {
-			writeError(w, err)
-			return
-		}
+	if err := s.awaitApproval(r); err != nil {
+		writeError(w, err)
+		return
 	}
 
+	record := usageRecordFromContext(r.Context())
+	record.setModel(payload.Model, "")
 	result, err := copilot.CreateEmbeddings(r.Context(), s.state, s.client, payload)
 	if err != nil {
 		writeError(w, err)
 		return
 	}
+	record.setTokens(result.Usage.PromptTokens, 0, 0)
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(result)
@@ -251,52 +443,73 @@ func (s *Server) writeModels(w http.ResponseWriter, models *copilot.ModelsRespon
 }
 
 func (s *Server) handleUsage(w http.ResponseWriter, r *http.Request) {
-	usage, err := github.GetCopilotUsage(r.Context(), s.state, s.client)
+	result, err := github.GetCopilotUsage(r.Context(), s.state, s.client)
 	if err != nil {
 		logger.Error("Error fetching Copilot usage: %v", err)
 		writeError(w, err)
 		return
 	}
+	usage.Store(s.state, result)
 
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(usage)
+	json.NewEncoder(w).Encode(result)
 }
 
 func (s *Server) handleMessages(w http.ResponseWriter, r *http.Request) {
-	if err := rate.CheckRateLimit(s.state); err != nil {
-		writeError(w, err)
+	if err := s.checkRateLimit(r); err != nil {
+		writeMessagesError(w, err)
 		return
 	}
 
 	body, err := io.ReadAll(r.Body)
 	if err != nil {
-		writeError(w, err)
+		writeMessagesError(w, err)
 		return
 	}
 	logger.Debug("Messages request payload (last 400 bytes): %s", truncateBody(body, 400))
 
 	var payload messages.AnthropicMessagesPayload
 	if err := json.Unmarshal(body, &payload); err != nil {
-		writeError(w, err)
+		writeAnthropicError(w, http.StatusBadRequest, "invalid_request_error", err.Error())
+		return
+	}
+
+	if err := s.awaitApproval(r); err != nil {
+		writeMessagesError(w, err)
+		return
+	}
+
+	openaiPayload, err := messages.TranslateToOpenAI(payload)
+	if err != nil {
+		writeAnthropicError(w, http.StatusBadRequest, "invalid_request_error", err.Error())
 		return
 	}
 
-	if s.manualApprove() {
-		if err := approval.AwaitApproval(); err != nil {
-			writeError(w, err)
+	openaiPayload, turn, err := s.expandConversation(r, openaiPayload)
+	if err != nil {
+		if errors.Is(err, responses.ErrConversationNotFound) {
+			writeAnthropicError(w, http.StatusNotFound, "not_found_error", err.Error())
 			return
 		}
+		writeMessagesError(w, err)
+		return
 	}
 
-	openaiPayload, err := messages.TranslateToOpenAI(payload)
+	openaiPayload, err = s.compact(w, r, openaiPayload)
 	if err != nil {
-		writeError(w, err)
+		writeMessagesError(w, err)
 		return
 	}
 
-	result, err := copilot.CreateChatCompletions(r.Context(), s.state, openaiPayload, s.client, s.streamer)
+	if payload.Thinking != nil && payload.Thinking.Type == "enabled" {
+		r = withThinking(r)
+	}
+	result, err := s.withFallback(w, r, s.fallbackChain(openaiPayload.Model, ""), openaiPayload.Messages, func(ctx context.Context, model string) (interface{}, error) {
+		openaiPayload.Model = model
+		return s.createChatCompletions(ctx, openaiPayload)
+	})
 	if err != nil {
-		writeError(w, err)
+		writeMessagesError(w, err)
 		return
 	}
 
@@ -304,19 +517,20 @@ func (s *Server) handleMessages(w http.ResponseWriter, r *http.Request) {
 
 	if streamRequested {
 		logger.Debug("Streaming messages response for model %s", payload.Model)
-		s.forwardMessagesStream(w, r, result)
+		s.forwardMessagesStream(w, r, s.recordConversationStream(r.Context(), result, turn))
 		return
 	}
+	s.appendConversationCompletion(turn, result)
 
 	completion, ok := result.(copilot.ChatCompletionResponse)
 	if !ok {
-		writeError(w, fmt.Errorf("unexpected response type"))
+		writeMessagesError(w, fmt.Errorf("unexpected response type"))
 		return
 	}
 
 	anthropic, err := messages.TranslateToAnthropic(completion)
 	if err != nil {
-		writeError(w, err)
+		writeMessagesError(w, err)
 		return
 	}
 
@@ -330,13 +544,13 @@ func (s *Server) handleMessages(w http.ResponseWriter, r *http.Request) {
 func (s *Server) forwardMessagesStream(w http.ResponseWriter, r *http.Request, result interface{}) {
 	ch, ok := result.(<-chan copilot.SSEMessage)
 	if !ok {
-		writeError(w, fmt.Errorf("invalid stream type"))
+		writeMessagesError(w, fmt.Errorf("invalid stream type"))
 		return
 	}
 
 	flusher, ok := w.(http.Flusher)
 	if !ok {
-		writeError(w, fmt.Errorf("streaming unsupported by server"))
+		writeMessagesError(w, fmt.Errorf("streaming unsupported by server"))
 		return
 	}
 
@@ -345,40 +559,55 @@ func (s *Server) forwardMessagesStream(w http.ResponseWriter, r *http.Request, r
 
 	ctx := r.Context()
 	streamState := messages.NewStreamState()
+	timers := s.newStreamTimers()
+	defer timers.stop()
 
 	for {
 		select {
 		case <-ctx.Done():
 			return
+		case <-timers.heartbeatC():
+			writeHeartbeat(w, dialectAnthropic)
+			flusher.Flush()
+			timers.wrote()
+		case <-timers.idleC():
+			writeStreamError(w, dialectAnthropic, timers.idleError())
+			flusher.Flush()
+			return
 		case chunk, ok := <-ch:
 			if !ok {
 				return
 			}
+			timers.received()
 			if chunk.Data == "[DONE]" {
 				logger.Debug("Messages stream finished with [DONE]")
 				return
 			}
+			if chunk.Err != nil {
+				writeStreamError(w, dialectAnthropic, chunk.Err)
+				flusher.Flush()
+				return
+			}
 
 			if chunk.Data == "" {
 				continue
 			}
+			if failure := appErr.ParseStreamError([]byte(chunk.Data)); failure != nil {
+				writeStreamError(w, dialectAnthropic, failure)
+				flusher.Flush()
+				return
+			}
 
 			var parsed copilot.ChatCompletionChunk
 			if err := json.Unmarshal([]byte(chunk.Data), &parsed); err != nil {
-				logger.Debug("Failed to decode stream chunk: %v", err)
-				stop := messages.TranslateStreamError()
-				fmt.Fprintf(w, "event: %s\n", stop.Type)
-				fmt.Fprintf(w, "data: %s\n\n", stop.Data)
+				writeStreamError(w, dialectAnthropic, fmt.Errorf("invalid stream chunk: %w", err))
 				flusher.Flush()
 				return
 			}
 
 			events, err := messages.TranslateChunkToAnthropicEvents(parsed, &streamState)
 			if err != nil {
-				logger.Debug("Failed to translate stream chunk: %v", err)
-				stop := messages.TranslateStreamError()
-				fmt.Fprintf(w, "event: %s\n", stop.Type)
-				fmt.Fprintf(w, "data: %s\n\n", stop.Data)
+				writeStreamError(w, dialectAnthropic, fmt.Errorf("failed to translate stream chunk: %w", err))
 				flusher.Flush()
 				return
 			}
@@ -406,7 +635,7 @@ func truncateBody(body []byte, limit int) string {
 }
 
 func (s *Server) handleResponses(w http.ResponseWriter, r *http.Request) {
-	if err := rate.CheckRateLimit(s.state); err != nil {
+	if err := s.checkRateLimit(r); err != nil {
 		writeError(w, err)
 		return
 	}
@@ -419,15 +648,23 @@ func (s *Server) handleResponses(w http.ResponseWriter, r *http.Request) {
 
 	var payload copilot.ResponsesPayload
 	if err := json.Unmarshal(rawBody, &payload); err != nil {
+		writeOpenAIError(w, http.StatusBadRequest, "invalid_request_error", err.Error())
+		return
+	}
+
+	if err := s.awaitApproval(r); err != nil {
 		writeError(w, err)
 		return
 	}
 
-	if manual := s.manualApprove(); manual {
-		if err := approval.AwaitApproval(); err != nil {
-			writeError(w, err)
-			return
-		}
+	rawBody, session, err := s.restoreResponsesState(r, rawBody)
+	if err != nil {
+		writeResponseStoreError(w, err)
+		return
+	}
+	if err := json.Unmarshal(rawBody, &payload); err != nil {
+		writeOpenAIError(w, http.StatusBadRequest, "invalid_request_error", err.Error())
+		return
 	}
 
 	streamRequested := payload.StreamEnabled()
@@ -442,47 +679,56 @@ func (s *Server) handleResponses(w http.ResponseWriter, r *http.Request) {
 		found := false
 		for _, model := range models.Data {
 			if model.ID == payload.Model {
-				for _, endpoint := range model.SupportedEndpoints {
-					if endpoint == "/responses" {
-						found = true
-						break
-					}
-				}
+				found = model.SupportsEndpoint("/responses")
 				break
 			}
 		}
 		if !found {
-			w.Header().Set("Content-Type", "application/json")
-			w.WriteHeader(http.StatusBadRequest)
-			json.NewEncoder(w).Encode(map[string]any{
-				"error": map[string]any{
-					"message": "This model does not support the responses endpoint. Please choose a different model.",
-					"type":    "invalid_request_error",
-				},
-			})
+			s.handleResponsesViaChat(w, r, rawBody, session)
 			return
 		}
 	}
 
 	messages := extractMessagesFromResponses(payload)
-	initiator := copilot.ResolveChatInitiator(payload.Model, messages)
 	vision := copilot.HasVisionInput(payload)
 
-	result, err := copilot.CreateResponses(r.Context(), s.state, rawBody, copilot.ResponsesRequestOptions{
-		Vision:    vision,
-		Initiator: initiator,
-		Stream:    streamRequested,
-	}, s.client, s.streamer)
+	result, err := s.withFallback(w, r, s.fallbackChain(payload.Model, "/responses"), messages, func(ctx context.Context, model string) (interface{}, error) {
+		body := rawBody
+		if model != payload.Model {
+			var err error
+			if body, err = withModel(rawBody, model); err != nil {
+				return nil, err
+			}
+		}
+		return s.createResponses(ctx, model, body, copilot.ResponsesRequestOptions{
+			Vision:    vision,
+			Initiator: copilot.ResolveChatInitiator(model, messages),
+			Stream:    streamRequested,
+		})
+	})
 	if err != nil {
 		writeError(w, err)
 		return
 	}
 
 	if streamRequested {
+		if stream, ok := result.(copilot.ResponsesStream); ok && session != nil {
+			result = s.recordResponsesStream(r.Context(), stream, session)
+		}
 		s.forwardStream(w, r, result)
 		return
 	}
 
+	if response, ok := result.(copilot.ResponsesResult); ok && session != nil {
+		if session.previousID != "" {
+			response["previous_response_id"] = session.previousID
+		}
+		if session.conversation != "" {
+			response["conversation"] = responses.ConversationRef{ID: session.conversation}
+		}
+		s.storeResponse(session, response)
+	}
+
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(result)
 }
@@ -0,0 +1,99 @@
+package server
+
+import (
+	"fmt"
+	"io"
+	"time"
+
+	appErr "internal/errors"
+)
+
+// streamDialect selects the event format written to a streaming client.
+type streamDialect int
+
+const (
+	dialectOpenAI streamDialect = iota
+	dialectAnthropic
+	dialectResponses
+)
+
+// streamTimers sends heartbeats while a client stream is silent and detects
+// upstreams that stop sending altogether. Disabled timers have nil channels,
+// which never fire in a select.
+type streamTimers struct {
+	heartbeatInterval time.Duration
+	idleTimeout       time.Duration
+	heartbeat         *time.Timer
+	idle              *time.Timer
+}
+
+func (s *Server) newStreamTimers() *streamTimers {
+	cfg := s.config().Streams
+	t := &streamTimers{}
+	if cfg.HeartbeatSeconds != nil && *cfg.HeartbeatSeconds > 0 {
+		t.heartbeatInterval = time.Duration(*cfg.HeartbeatSeconds) * time.Second
+		t.heartbeat = time.NewTimer(t.heartbeatInterval)
+	}
+	if cfg.IdleTimeoutSeconds != nil && *cfg.IdleTimeoutSeconds > 0 {
+		t.idleTimeout = time.Duration(*cfg.IdleTimeoutSeconds) * time.Second
+		t.idle = time.NewTimer(t.idleTimeout)
+	}
+	return t
+}
+
+func (t *streamTimers) heartbeatC() <-chan time.Time {
+	if t.heartbeat == nil {
+		return nil
+	}
+	return t.heartbeat.C
+}
+
+func (t *streamTimers) idleC() <-chan time.Time {
+	if t.idle == nil {
+		return nil
+	}
+	return t.idle.C
+}
+
+// received restarts both timers after upstream sent something.
+func (t *streamTimers) received() {
+	if t.idle != nil {
+		t.idle.Reset(t.idleTimeout)
+	}
+	t.wrote()
+}
+
+// wrote restarts the heartbeat timer after anything was sent to the client.
+func (t *streamTimers) wrote() {
+	if t.heartbeat != nil {
+		t.heartbeat.Reset(t.heartbeatInterval)
+	}
+}
+
+func (t *streamTimers) stop() {
+	if t.heartbeat != nil {
+		t.heartbeat.Stop()
+	}
+	if t.idle != nil {
+		t.idle.Stop()
+	}
+}
+
+// writeHeartbeat keeps an idle client connection open: Anthropic clients
+// get a ping event, everything else an SSE comment.
+func writeHeartbeat(w io.Writer, dialect streamDialect) {
+	if dialect == dialectAnthropic {
+		fmt.Fprint(w, "event: ping\ndata: {\"type\":\"ping\"}\n\n")
+		return
+	}
+	fmt.Fprint(w, ": keepalive\n\n")
+}
+
+// idleError describes an upstream that stopped sending.
+func (t *streamTimers) idleError() error {
+	return &appErr.StreamError{
+		Message: fmt.Sprintf("Upstream sent no data for %s; the request was aborted.", t.idleTimeout),
+		Type:    "timeout_error",
+		Code:    "upstream_timeout",
+	}
+}
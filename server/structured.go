@@ -0,0 +1,149 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"internal/jsonschema"
+	"internal/logger"
+	"internal/services/copilot"
+)
+
+const structuredOutputPrompt = "Respond only with a single JSON value that conforms to the JSON Schema below. " +
+	"Do not wrap it in code fences and do not add any other text."
+
+// structuredOutput tracks a json_schema response_format for one request.
+type structuredOutput struct {
+	schema *jsonschema.Schema
+	// prompted is set when the schema is enforced through the system prompt
+	// because the model lacks native structured outputs.
+	prompted bool
+}
+
+func (o *structuredOutput) mode() string {
+	if o.prompted {
+		return "prompt"
+	}
+	return "native"
+}
+
+// prepareStructuredOutput compiles the json_schema response_format of payload.
+// Models without structured outputs get the schema as a system instruction
+// instead. A nil structuredOutput means there is nothing to validate.
+func (s *Server) prepareStructuredOutput(payload copilot.ChatCompletionsPayload) (copilot.ChatCompletionsPayload, *structuredOutput, error) {
+	format := payload.ResponseFormat
+	if format == nil || format.Type != "json_schema" || format.JSONSchema == nil || len(format.JSONSchema.Schema) == 0 {
+		return payload, nil, nil
+	}
+
+	schema, err := jsonschema.Compile(format.JSONSchema.Schema)
+	if err != nil {
+		return payload, nil, fmt.Errorf("response_format.json_schema.schema: %w", err)
+	}
+	output := &structuredOutput{schema: schema}
+
+	model, ok := s.findModel(payload.Model)
+	if !ok || (model.Capabilities.Supports.StructuredOutputs != nil && *model.Capabilities.Supports.StructuredOutputs) {
+		return payload, output, nil
+	}
+
+	logger.Debug("Model %s lacks structured outputs; enforcing schema %s through the prompt", payload.Model, format.JSONSchema.Name)
+	output.prompted = true
+
+	instruction := structuredOutputPrompt
+	if format.JSONSchema.Description != nil && *format.JSONSchema.Description != "" {
+		instruction += "\n\nThe value describes: " + *format.JSONSchema.Description
+	}
+	instruction += "\n\n" + string(format.JSONSchema.Schema)
+
+	payload.ResponseFormat = nil
+	payload.Messages = append([]copilot.Message{{
+		Role:    "system",
+		Content: copilot.MessageContent{StringValue: &instruction},
+	}}, payload.Messages...)
+	return payload, output, nil
+}
+
+// check validates every text choice, stripping code fences from prompted
+// output in place. It returns the offending content and error of the first
+// invalid choice.
+func (o *structuredOutput) check(completion *copilot.ChatCompletionResponse) (string, error) {
+	for i := range completion.Choices {
+		choice := &completion.Choices[i]
+		if len(choice.Message.ToolCalls) > 0 {
+			continue
+		}
+		text := choice.Message.Content.Text()
+		if o.prompted {
+			text = stripCodeFence(text)
+			choice.Message.Content = copilot.MessageContent{StringValue: &text}
+		}
+		if err := o.schema.ValidateJSON([]byte(text)); err != nil {
+			return text, err
+		}
+	}
+	return "", nil
+}
+
+// createStructuredCompletion runs a non-streaming completion and retries it
+// with the validation error as feedback until the output matches the schema
+// or the configured retries are exhausted. The last response is returned
+// either way; headers on w report the outcome.
+func (s *Server) createStructuredCompletion(ctx context.Context, w http.ResponseWriter, payload copilot.ChatCompletionsPayload, output *structuredOutput) (interface{}, error) {
+	retries := *s.config().StructuredOutputs.Retries
+	w.Header().Set("X-Structured-Output-Mode", output.mode())
+
+	current := payload
+	for attempt := 1; ; attempt++ {
+		result, err := s.createChatCompletions(ctx, current)
+		if err != nil {
+			return nil, err
+		}
+		completion, ok := result.(copilot.ChatCompletionResponse)
+		if !ok {
+			return result, nil
+		}
+
+		content, invalid := output.check(&completion)
+		w.Header().Set("X-Structured-Output-Attempts", strconv.Itoa(attempt))
+		if invalid == nil {
+			return completion, nil
+		}
+		if attempt > retries {
+			logger.Warn("Structured output for model %s still invalid after %d attempts: %v", payload.Model, attempt, invalid)
+			w.Header().Set("X-Structured-Output-Error", invalid.Error())
+			return completion, nil
+		}
+
+		if err := reserveUpstreamCalls(ctx, 1); err != nil {
+			logger.Warn("Structured output for model %s not retried: %v", payload.Model, err)
+			w.Header().Set("X-Structured-Output-Error", invalid.Error())
+			return completion, nil
+		}
+
+		logger.Info("Structured output for model %s failed validation (attempt %d): %v", payload.Model, attempt, invalid)
+		feedback := fmt.Sprintf("Your previous reply did not match the required JSON Schema (%v). Reply again with only the corrected JSON.", invalid)
+		current = payload
+		current.Messages = append(append([]copilot.Message{}, payload.Messages...),
+			copilot.Message{Role: "assistant", Content: copilot.MessageContent{StringValue: &content}},
+			copilot.Message{Role: "user", Content: copilot.MessageContent{StringValue: &feedback}},
+		)
+	}
+}
+
+// stripCodeFence removes a surrounding ```json fence that prompted models
+// tend to add despite instructions.
+func stripCodeFence(text string) string {
+	trimmed := strings.TrimSpace(text)
+	if !strings.HasPrefix(trimmed, "```") || !strings.HasSuffix(trimmed, "```") || len(trimmed) < 6 {
+		return trimmed
+	}
+	body := strings.TrimSuffix(trimmed[3:], "```")
+	if newline := strings.IndexByte(body, '\n'); newline >= 0 {
+		body = body[newline+1:]
+	}
+	return strings.TrimSpace(body)
+}
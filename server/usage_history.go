@@ -0,0 +1,50 @@
+package server
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"internal/usage"
+)
+
+const defaultHistoryDays = 30
+
+// EnableUsageHistory serves trends of the quota snapshots in h on
+// /admin/usage-history. Once auth is enabled only API_KEY may read them.
+func (s *Server) EnableUsageHistory(h *usage.History) {
+	s.usageHistory = h
+	s.mux.Handle("GET /admin/usage-history", Chain(http.HandlerFunc(s.handleUsageHistory), s.APIKeyMiddleware))
+}
+
+func (s *Server) handleUsageHistory(w http.ResponseWriter, r *http.Request) {
+	if apiKeyFromContext(r.Context()) != nil && !isEnvKey(r) {
+		writeOpenAIError(w, http.StatusForbidden, "permission_error", "usage history is only available to API_KEY")
+		return
+	}
+	query := r.URL.Query()
+	days := defaultHistoryDays
+	if value := query.Get("days"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed <= 0 || parsed > usage.MaxTrendDays {
+			writeOpenAIError(w, http.StatusBadRequest, "invalid_request_error", "days: must be between 1 and "+strconv.Itoa(usage.MaxTrendDays))
+			return
+		}
+		days = parsed
+	}
+
+	now := time.Now()
+	snapshots, err := s.usageHistory.Read(now.AddDate(0, 0, -max(days, 7)-1))
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	trends := usage.FilterTrends(usage.Trends(snapshots, days, now, time.Local), query.Get("quota"))
+
+	if query.Get("format") == "text" {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		usage.WriteTrends(w, trends)
+		return
+	}
+	writeJSON(w, map[string]any{"object": "list", "data": trends})
+}
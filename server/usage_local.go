@@ -0,0 +1,208 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"internal/logger"
+	"internal/services/copilot"
+	"internal/usage"
+)
+
+const usageRecordContextKey contextKey = "usage-record"
+
+// usageRecord collects what handlers learn about a request for its ledger
+// entry. Streams fill in token counts from a separate goroutine.
+type usageRecord struct {
+	mu    sync.Mutex
+	entry usage.Entry
+}
+
+func usageRecordFromContext(ctx context.Context) *usageRecord {
+	record, _ := ctx.Value(usageRecordContextKey).(*usageRecord)
+	return record
+}
+
+func (u *usageRecord) setModel(model, initiator string) {
+	if u == nil {
+		return
+	}
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	u.entry.Model = model
+	u.entry.Initiator = initiator
+}
+
+func (u *usageRecord) setTokens(prompt, completion, cached int) {
+	if u == nil {
+		return
+	}
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	u.entry.PromptTokens = prompt
+	u.entry.CompletionTokens = completion
+	u.entry.CachedTokens = cached
+}
+
+// EnableUsageLedger appends every completed model request to the ledger at
+// path and serves aggregated reports on /usage/local.
+func (s *Server) EnableUsageLedger(path string) {
+	s.usageLedger = usage.NewLedger(path)
+	s.mux.Handle("GET /usage/local", Chain(http.HandlerFunc(s.handleLocalUsage), s.APIKeyMiddleware))
+}
+
+// recordUsage writes a ledger entry once the request has been served.
+func (s *Server) recordUsage(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if s.usageLedger == nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+		start := time.Now()
+		record := &usageRecord{entry: usage.Entry{Route: r.URL.Path, Key: ownerName(r)}}
+		wrapped := &statusWriter{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(wrapped, r.WithContext(context.WithValue(r.Context(), usageRecordContextKey, record)))
+
+		record.mu.Lock()
+		entry := record.entry
+		record.mu.Unlock()
+		entry.Timestamp = time.Now().UnixMilli()
+		entry.LatencyMs = time.Since(start).Milliseconds()
+		entry.Status = wrapped.status
+		if err := s.usageLedger.Append(entry); err != nil {
+			logger.Error("Failed to write usage ledger: %v", err)
+		}
+	})
+}
+
+// observeUsage takes token counts from a result for the request's ledger
+// entry. Streams are passed through a goroutine that watches for usage.
+func observeUsage(ctx context.Context, result interface{}) interface{} {
+	record := usageRecordFromContext(ctx)
+	if record == nil {
+		return result
+	}
+	switch typed := result.(type) {
+	case copilot.ChatCompletionResponse:
+		if typed.Usage != nil {
+			cached := 0
+			if typed.Usage.PromptTokensDetails != nil {
+				cached = typed.Usage.PromptTokensDetails.CachedTokens
+			}
+			record.setTokens(typed.Usage.PromptTokens, typed.Usage.CompletionTokens, cached)
+		}
+	case copilot.ResponsesResult:
+		if data, err := json.Marshal(typed); err == nil {
+			recordStreamUsage(record, string(data))
+		}
+	case <-chan copilot.SSEMessage:
+		return teeUsage(ctx, typed, record)
+	case copilot.ResponsesStream:
+		return copilot.ResponsesStream(teeUsage(ctx, typed, record))
+	}
+	return result
+}
+
+func teeUsage(ctx context.Context, in <-chan copilot.SSEMessage, record *usageRecord) <-chan copilot.SSEMessage {
+	out := make(chan copilot.SSEMessage)
+	go func() {
+		defer close(out)
+		for msg := range in {
+			if strings.Contains(msg.Data, `"usage"`) {
+				recordStreamUsage(record, msg.Data)
+			}
+			select {
+			case out <- msg:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return out
+}
+
+// recordStreamUsage reads usage from a chat chunk, a Responses object or a
+// Responses event wrapping one.
+func recordStreamUsage(record *usageRecord, data string) {
+	type tokenUsage struct {
+		PromptTokens        int `json:"prompt_tokens"`
+		CompletionTokens    int `json:"completion_tokens"`
+		InputTokens         int `json:"input_tokens"`
+		OutputTokens        int `json:"output_tokens"`
+		PromptTokensDetails *struct {
+			CachedTokens int `json:"cached_tokens"`
+		} `json:"prompt_tokens_details"`
+		InputTokensDetails *struct {
+			CachedTokens int `json:"cached_tokens"`
+		} `json:"input_tokens_details"`
+	}
+	var payload struct {
+		Usage    *tokenUsage `json:"usage"`
+		Response *struct {
+			Usage *tokenUsage `json:"usage"`
+		} `json:"response"`
+	}
+	if json.Unmarshal([]byte(data), &payload) != nil {
+		return
+	}
+	found := payload.Usage
+	if found == nil && payload.Response != nil {
+		found = payload.Response.Usage
+	}
+	if found == nil {
+		return
+	}
+	cached := 0
+	if found.PromptTokensDetails != nil {
+		cached = found.PromptTokensDetails.CachedTokens
+	} else if found.InputTokensDetails != nil {
+		cached = found.InputTokensDetails.CachedTokens
+	}
+	record.setTokens(found.PromptTokens+found.InputTokens, found.CompletionTokens+found.OutputTokens, cached)
+}
+
+func (s *Server) handleLocalUsage(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	groupBy, err := usage.ParseGroupBy(query.Get("group_by"))
+	if err != nil {
+		writeOpenAIError(w, http.StatusBadRequest, "invalid_request_error", err.Error())
+		return
+	}
+	since, err := usage.ParseDay(query.Get("since"), time.Local)
+	if err != nil {
+		writeOpenAIError(w, http.StatusBadRequest, "invalid_request_error", "since: expected YYYY-MM-DD")
+		return
+	}
+	until, err := usage.ParseDay(query.Get("until"), time.Local)
+	if err != nil {
+		writeOpenAIError(w, http.StatusBadRequest, "invalid_request_error", "until: expected YYYY-MM-DD")
+		return
+	}
+	if !until.IsZero() {
+		until = until.AddDate(0, 0, 1)
+	}
+
+	entries, err := s.usageLedger.Read(since, until)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	// Keys from config.json only see their own requests.
+	if apiKeyFromContext(r.Context()) != nil && !isEnvKey(r) {
+		owner := ownerName(r)
+		entries = slices.DeleteFunc(entries, func(entry usage.Entry) bool { return entry.Key != owner })
+	}
+	rows := usage.Aggregate(entries, groupBy, time.Local)
+
+	if query.Get("format") == "table" {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		usage.WriteTable(w, rows, groupBy)
+		return
+	}
+	writeJSON(w, map[string]any{"object": "list", "group_by": groupBy, "data": rows})
+}
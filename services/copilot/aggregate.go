@@ -0,0 +1,301 @@
+package copilot
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	appErr "internal/errors"
+)
+
+type choiceAccumulator struct {
+	role         string
+	content      strings.Builder
+	hasContent   bool
+	reasoning    strings.Builder
+	hasReasoning bool
+	toolCalls    map[int]*ToolCall
+	finishReason string
+}
+
+// AggregateStream reassembles a chat completion stream into the response a
+// non-streaming request would have returned: content and reasoning are
+// concatenated per choice, tool call arguments are joined by call index, and
+// the last finish reason and usage win.
+func AggregateStream(ctx context.Context, stream <-chan SSEMessage) (ChatCompletionResponse, error) {
+	var response ChatCompletionResponse
+	choices := make(map[int]*choiceAccumulator)
+	received := false
+
+	for {
+		var msg SSEMessage
+		var ok bool
+		select {
+		case <-ctx.Done():
+			return response, ctx.Err()
+		case msg, ok = <-stream:
+		}
+		if !ok || msg.Data == "[DONE]" {
+			break
+		}
+		if msg.Err != nil {
+			return response, msg.Err
+		}
+		if msg.Data == "" {
+			continue
+		}
+
+		if failure := appErr.ParseStreamError([]byte(msg.Data)); failure != nil {
+			return response, failure
+		}
+		var chunk ChatCompletionChunk
+		if err := json.Unmarshal([]byte(msg.Data), &chunk); err != nil {
+			return response, fmt.Errorf("invalid stream chunk: %w", err)
+		}
+		received = true
+
+		if response.ID == "" {
+			response.ID = chunk.ID
+			response.Created = chunk.Created
+		}
+		if chunk.Model != "" {
+			response.Model = chunk.Model
+		}
+		if chunk.SystemFingerprint != nil {
+			response.SystemFingerprint = chunk.SystemFingerprint
+		}
+		if chunk.Usage != nil {
+			response.Usage = &ChatUsage{
+				PromptTokens:            chunk.Usage.PromptTokens,
+				CompletionTokens:        chunk.Usage.CompletionTokens,
+				TotalTokens:             chunk.Usage.TotalTokens,
+				PromptTokensDetails:     chunk.Usage.PromptTokensDetails,
+				CompletionTokensDetails: chunk.Usage.CompletionTokensDetails,
+			}
+		}
+
+		for _, choice := range chunk.Choices {
+			acc, ok := choices[choice.Index]
+			if !ok {
+				acc = &choiceAccumulator{role: "assistant", toolCalls: make(map[int]*ToolCall)}
+				choices[choice.Index] = acc
+			}
+			delta := choice.Delta
+			if delta.Role != nil && *delta.Role != "" {
+				acc.role = *delta.Role
+			}
+			if delta.Content != nil {
+				acc.content.WriteString(*delta.Content)
+				acc.hasContent = true
+			}
+			if delta.ReasoningText != nil {
+				acc.reasoning.WriteString(*delta.ReasoningText)
+				acc.hasReasoning = true
+			}
+			for _, call := range delta.ToolCalls {
+				existing, ok := acc.toolCalls[call.Index]
+				if !ok {
+					existing = &ToolCall{Index: call.Index, Type: "function"}
+					acc.toolCalls[call.Index] = existing
+				}
+				if call.ID != "" {
+					existing.ID = call.ID
+				}
+				if call.Type != "" {
+					existing.Type = call.Type
+				}
+				if call.Function.Name != "" {
+					existing.Function.Name = call.Function.Name
+				}
+				existing.Function.Arguments += call.Function.Arguments
+			}
+			if choice.FinishReason != nil && *choice.FinishReason != "" {
+				acc.finishReason = *choice.FinishReason
+			}
+		}
+	}
+
+	if !received {
+		return response, fmt.Errorf("upstream stream ended without data")
+	}
+
+	response.Object = "chat.completion"
+	indexes := make([]int, 0, len(choices))
+	for index := range choices {
+		indexes = append(indexes, index)
+	}
+	sort.Ints(indexes)
+	response.Choices = make([]ChoiceNonStreaming, 0, len(indexes))
+	for _, index := range indexes {
+		acc := choices[index]
+		message := ResponseMessage{Role: acc.role}
+		if acc.hasContent {
+			content := acc.content.String()
+			message.Content = MessageContent{StringValue: &content}
+		}
+		if acc.hasReasoning {
+			reasoning := acc.reasoning.String()
+			message.ReasoningText = &reasoning
+		}
+
+		callIndexes := make([]int, 0, len(acc.toolCalls))
+		for callIndex := range acc.toolCalls {
+			callIndexes = append(callIndexes, callIndex)
+		}
+		sort.Ints(callIndexes)
+		for _, callIndex := range callIndexes {
+			call := *acc.toolCalls[callIndex]
+			call.Index = 0
+			message.ToolCalls = append(message.ToolCalls, call)
+		}
+
+		finishReason := acc.finishReason
+		if finishReason == "" {
+			finishReason = "stop"
+			if len(message.ToolCalls) > 0 {
+				finishReason = "tool_calls"
+			}
+		}
+		response.Choices = append(response.Choices, ChoiceNonStreaming{
+			Index:        index,
+			Message:      message,
+			FinishReason: finishReason,
+		})
+	}
+	return response, nil
+}
+
+// AggregateResponsesStream returns the response carried by the terminal
+// event of a Responses stream. Error events and failed responses are
+// returned as a *appErr.StreamError.
+func AggregateResponsesStream(ctx context.Context, stream ResponsesStream) (ResponsesResult, error) {
+	for {
+		var msg SSEMessage
+		var ok bool
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case msg, ok = <-stream:
+		}
+		if !ok {
+			return nil, fmt.Errorf("upstream stream ended without a completed response")
+		}
+		if msg.Err != nil {
+			return nil, msg.Err
+		}
+		if msg.Data == "" || msg.Data == "[DONE]" {
+			continue
+		}
+
+		if failure := appErr.ParseStreamError([]byte(msg.Data)); failure != nil {
+			return nil, failure
+		}
+		var event struct {
+			Type     string          `json:"type"`
+			Response ResponsesResult `json:"response"`
+		}
+		if err := json.Unmarshal([]byte(msg.Data), &event); err != nil {
+			return nil, fmt.Errorf("invalid stream event: %w", err)
+		}
+		switch event.Type {
+		case "response.completed", "response.incomplete":
+			return event.Response, nil
+		case "response.failed":
+			return nil, responseFailure(event.Response)
+		}
+	}
+}
+
+// responseFailure turns the error of a failed response into a StreamError.
+func responseFailure(response ResponsesResult) *appErr.StreamError {
+	failure := &appErr.StreamError{Message: "response failed", Type: "response.failed"}
+	if details, ok := response["error"].(map[string]any); ok {
+		if message, ok := details["message"].(string); ok && message != "" {
+			failure.Message = message
+		}
+		if code, ok := details["code"].(string); ok {
+			failure.Code = code
+		}
+	}
+	return failure
+}
+
+// SynthesizeStream replays a complete response as a chat completion stream
+// for clients that asked to stream from a model that cannot. Each choice
+// gets a role chunk, its reasoning and content, one chunk opening and one
+// carrying the arguments of every tool call, and a finish chunk; usage rides
+// on the last finish chunk, followed by [DONE].
+func SynthesizeStream(ctx context.Context, response ChatCompletionResponse) <-chan SSEMessage {
+	var chunks []ChatCompletionChunk
+	chunk := func(index int, delta Delta, finishReason *string) {
+		chunks = append(chunks, ChatCompletionChunk{
+			ID:                response.ID,
+			Object:            "chat.completion.chunk",
+			Created:           response.Created,
+			Model:             response.Model,
+			SystemFingerprint: response.SystemFingerprint,
+			Choices:           []Choice{{Index: index, Delta: delta, FinishReason: finishReason}},
+		})
+	}
+
+	for _, choice := range response.Choices {
+		message := choice.Message
+		role := message.Role
+		if role == "" {
+			role = "assistant"
+		}
+		chunk(choice.Index, Delta{Role: &role, ReasoningText: message.ReasoningText}, nil)
+
+		if text := message.Content.Text(); text != "" {
+			chunk(choice.Index, Delta{Content: &text}, nil)
+		}
+		for i, call := range message.ToolCalls {
+			open := ToolCall{Index: i, ID: call.ID, Type: call.Type, Function: ToolCallFunction{Name: call.Function.Name}}
+			if open.Type == "" {
+				open.Type = "function"
+			}
+			chunk(choice.Index, Delta{ToolCalls: []ToolCall{open}}, nil)
+			if call.Function.Arguments != "" {
+				chunk(choice.Index, Delta{ToolCalls: []ToolCall{{Index: i, Function: ToolCallFunction{Arguments: call.Function.Arguments}}}}, nil)
+			}
+		}
+
+		finishReason := choice.FinishReason
+		if finishReason == "" {
+			finishReason = "stop"
+		}
+		chunk(choice.Index, Delta{}, &finishReason)
+	}
+	if response.Usage != nil && len(chunks) > 0 {
+		chunks[len(chunks)-1].Usage = &UsageDetails{
+			PromptTokens:            response.Usage.PromptTokens,
+			CompletionTokens:        response.Usage.CompletionTokens,
+			TotalTokens:             response.Usage.TotalTokens,
+			PromptTokensDetails:     response.Usage.PromptTokensDetails,
+			CompletionTokensDetails: response.Usage.CompletionTokensDetails,
+		}
+	}
+
+	out := make(chan SSEMessage)
+	go func() {
+		defer close(out)
+		for _, c := range chunks {
+			data, err := json.Marshal(c)
+			if err != nil {
+				continue
+			}
+			select {
+			case out <- SSEMessage{Data: string(data)}:
+			case <-ctx.Done():
+				return
+			}
+		}
+		select {
+		case out <- SSEMessage{Data: "[DONE]"}:
+		case <-ctx.Done():
+		}
+	}()
+	return out
+}
@@ -5,9 +5,9 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"io"
 	"net/http"
 	"strings"
+	"time"
 
 	"internal/api"
 	"internal/errors"
@@ -15,7 +15,8 @@ import (
 	"internal/state"
 )
 
-// ChatCompletionsPayload mirrors the TypeScript payload definition.
+// ChatCompletionsPayload mirrors the TypeScript payload definition. Members
+// without a typed field are kept in Extras and forwarded unchanged.
 type ChatCompletionsPayload struct {
 	Messages         []Message          `json:"messages"`
 	Model            string             `json:"model"`
@@ -34,22 +35,95 @@ type ChatCompletionsPayload struct {
 	Tools            []Tool             `json:"tools,omitempty"`
 	ToolChoice       *ToolChoice        `json:"tool_choice,omitempty"`
 	User             *string            `json:"user,omitempty"`
+
+	ReasoningEffort     *string        `json:"reasoning_effort,omitempty"`
+	MaxCompletionTokens *int           `json:"max_completion_tokens,omitempty"`
+	ParallelToolCalls   *bool          `json:"parallel_tool_calls,omitempty"`
+	StreamOptions       *StreamOptions `json:"stream_options,omitempty"`
+	Prediction          *Prediction    `json:"prediction,omitempty"`
+	Modalities          []string       `json:"modalities,omitempty"`
+
+	Extras Extras `json:"-"`
+}
+
+type StreamOptions struct {
+	IncludeUsage *bool `json:"include_usage,omitempty"`
+}
+
+// Prediction mirrors { type: "content", content: string | ContentPart[] }.
+type Prediction struct {
+	Type    string      `json:"type"`
+	Content interface{} `json:"content"`
+}
+
+func (p ChatCompletionsPayload) MarshalJSON() ([]byte, error) {
+	type plain ChatCompletionsPayload
+	return EncodeWithExtras(plain(p), p.Extras)
+}
+
+func (p *ChatCompletionsPayload) UnmarshalJSON(data []byte) error {
+	type plain ChatCompletionsPayload
+	extras, err := DecodeWithExtras(data, (*plain)(p))
+	p.Extras = extras
+	return err
 }
 
-// ResponseFormat mirrors { type: "json_object" } | null
+// ResponseFormat mirrors { type: "text" | "json_object" | "json_schema", json_schema? } | null
 type ResponseFormat struct {
-	Type string `json:"type"`
+	Type       string            `json:"type"`
+	JSONSchema *JSONSchemaFormat `json:"json_schema,omitempty"`
+}
+
+// JSONSchemaFormat is the json_schema member of a structured outputs request.
+type JSONSchemaFormat struct {
+	Name        string          `json:"name"`
+	Description *string         `json:"description,omitempty"`
+	Schema      json.RawMessage `json:"schema,omitempty"`
+	Strict      *bool           `json:"strict,omitempty"`
 }
 
 type Tool struct {
-	Type     string   `json:"type"`
-	Function Function `json:"function"`
+	Type                string        `json:"type"`
+	Function            Function      `json:"function"`
+	CopilotCacheControl *CacheControl `json:"copilot_cache_control,omitempty"`
+	Extras              Extras        `json:"-"`
+}
+
+func (t Tool) MarshalJSON() ([]byte, error) {
+	type plain Tool
+	return EncodeWithExtras(plain(t), t.Extras)
+}
+
+func (t *Tool) UnmarshalJSON(data []byte) error {
+	type plain Tool
+	extras, err := DecodeWithExtras(data, (*plain)(t))
+	t.Extras = extras
+	return err
+}
+
+// CacheControl marks a prompt-caching breakpoint; Copilot honours it for Claude models.
+type CacheControl struct {
+	Type string  `json:"type"`
+	TTL  *string `json:"ttl,omitempty"`
 }
 
 type Function struct {
 	Name        string      `json:"name"`
 	Description *string     `json:"description,omitempty"`
 	Parameters  interface{} `json:"parameters"`
+	Extras      Extras      `json:"-"`
+}
+
+func (f Function) MarshalJSON() ([]byte, error) {
+	type plain Function
+	return EncodeWithExtras(plain(f), f.Extras)
+}
+
+func (f *Function) UnmarshalJSON(data []byte) error {
+	type plain Function
+	extras, err := DecodeWithExtras(data, (*plain)(f))
+	f.Extras = extras
+	return err
 }
 
 // ToolChoice can be a string enum or an object.
@@ -101,11 +175,25 @@ func (t *ToolChoice) UnmarshalJSON(data []byte) error {
 }
 
 type Message struct {
-	Role       string         `json:"role"`
-	Content    MessageContent `json:"content"`
-	Name       *string        `json:"name,omitempty"`
-	ToolCalls  []ToolCall     `json:"tool_calls,omitempty"`
-	ToolCallID *string        `json:"tool_call_id,omitempty"`
+	Role                string         `json:"role"`
+	Content             MessageContent `json:"content"`
+	Name                *string        `json:"name,omitempty"`
+	ToolCalls           []ToolCall     `json:"tool_calls,omitempty"`
+	ToolCallID          *string        `json:"tool_call_id,omitempty"`
+	CopilotCacheControl *CacheControl  `json:"copilot_cache_control,omitempty"`
+	Extras              Extras         `json:"-"`
+}
+
+func (m Message) MarshalJSON() ([]byte, error) {
+	type plain Message
+	return EncodeWithExtras(plain(m), m.Extras)
+}
+
+func (m *Message) UnmarshalJSON(data []byte) error {
+	type plain Message
+	extras, err := DecodeWithExtras(data, (*plain)(m))
+	m.Extras = extras
+	return err
 }
 
 // MessageContent can be string, []ContentPart or null.
@@ -118,6 +206,19 @@ type ContentPart struct {
 	Type     string        `json:"type"`
 	Text     *string       `json:"text,omitempty"`
 	ImageURL *ContentImage `json:"image_url,omitempty"`
+	Extras   Extras        `json:"-"`
+}
+
+func (p ContentPart) MarshalJSON() ([]byte, error) {
+	type plain ContentPart
+	return EncodeWithExtras(plain(p), p.Extras)
+}
+
+func (p *ContentPart) UnmarshalJSON(data []byte) error {
+	type plain ContentPart
+	extras, err := DecodeWithExtras(data, (*plain)(p))
+	p.Extras = extras
+	return err
 }
 
 type ContentImage struct {
@@ -125,6 +226,21 @@ type ContentImage struct {
 	Detail *string `json:"detail,omitempty"`
 }
 
+// Text returns the content as plain text: the string form, or the text
+// parts joined together. Image parts are left out.
+func (c MessageContent) Text() string {
+	if c.StringValue != nil {
+		return *c.StringValue
+	}
+	var b strings.Builder
+	for _, part := range c.Parts {
+		if part.Type == "text" && part.Text != nil {
+			b.WriteString(*part.Text)
+		}
+	}
+	return b.String()
+}
+
 func (c MessageContent) MarshalJSON() ([]byte, error) {
 	switch {
 	case c.StringValue != nil:
@@ -176,17 +292,58 @@ type ChatCompletionResponse struct {
 	Choices           []ChoiceNonStreaming `json:"choices"`
 	SystemFingerprint *string              `json:"system_fingerprint,omitempty"`
 	Usage             *ChatUsage           `json:"usage,omitempty"`
+	Extras            Extras               `json:"-"`
+}
+
+func (r ChatCompletionResponse) MarshalJSON() ([]byte, error) {
+	type plain ChatCompletionResponse
+	return EncodeWithExtras(plain(r), r.Extras)
+}
+
+func (r *ChatCompletionResponse) UnmarshalJSON(data []byte) error {
+	type plain ChatCompletionResponse
+	extras, err := DecodeWithExtras(data, (*plain)(r))
+	r.Extras = extras
+	return err
 }
 
 type ChatUsage struct {
-	PromptTokens        int                  `json:"prompt_tokens"`
-	CompletionTokens    int                  `json:"completion_tokens"`
-	TotalTokens         int                  `json:"total_tokens"`
-	PromptTokensDetails *PromptTokensDetails `json:"prompt_tokens_details,omitempty"`
+	PromptTokens            int                      `json:"prompt_tokens"`
+	CompletionTokens        int                      `json:"completion_tokens"`
+	TotalTokens             int                      `json:"total_tokens"`
+	PromptTokensDetails     *PromptTokensDetails     `json:"prompt_tokens_details,omitempty"`
+	CompletionTokensDetails *CompletionTokensDetails `json:"completion_tokens_details,omitempty"`
+	Extras                  Extras                   `json:"-"`
+}
+
+func (u ChatUsage) MarshalJSON() ([]byte, error) {
+	type plain ChatUsage
+	return EncodeWithExtras(plain(u), u.Extras)
+}
+
+func (u *ChatUsage) UnmarshalJSON(data []byte) error {
+	type plain ChatUsage
+	extras, err := DecodeWithExtras(data, (*plain)(u))
+	u.Extras = extras
+	return err
 }
 
 type PromptTokensDetails struct {
-	CachedTokens int `json:"cached_tokens"`
+	CachedTokens             int    `json:"cached_tokens"`
+	CacheCreationInputTokens int    `json:"cache_creation_input_tokens,omitempty"`
+	Extras                   Extras `json:"-"`
+}
+
+func (d PromptTokensDetails) MarshalJSON() ([]byte, error) {
+	type plain PromptTokensDetails
+	return EncodeWithExtras(plain(d), d.Extras)
+}
+
+func (d *PromptTokensDetails) UnmarshalJSON(data []byte) error {
+	type plain PromptTokensDetails
+	extras, err := DecodeWithExtras(data, (*plain)(d))
+	d.Extras = extras
+	return err
 }
 
 type Choice struct {
@@ -194,6 +351,19 @@ type Choice struct {
 	Delta        Delta   `json:"delta"`
 	FinishReason *string `json:"finish_reason"`
 	Logprobs     any     `json:"logprobs"`
+	Extras       Extras  `json:"-"`
+}
+
+func (c Choice) MarshalJSON() ([]byte, error) {
+	type plain Choice
+	return EncodeWithExtras(plain(c), c.Extras)
+}
+
+func (c *Choice) UnmarshalJSON(data []byte) error {
+	type plain Choice
+	extras, err := DecodeWithExtras(data, (*plain)(c))
+	c.Extras = extras
+	return err
 }
 
 type ChoiceNonStreaming struct {
@@ -201,18 +371,60 @@ type ChoiceNonStreaming struct {
 	Message      ResponseMessage `json:"message"`
 	Logprobs     any             `json:"logprobs"`
 	FinishReason string          `json:"finish_reason"`
+	Extras       Extras          `json:"-"`
+}
+
+func (c ChoiceNonStreaming) MarshalJSON() ([]byte, error) {
+	type plain ChoiceNonStreaming
+	return EncodeWithExtras(plain(c), c.Extras)
+}
+
+func (c *ChoiceNonStreaming) UnmarshalJSON(data []byte) error {
+	type plain ChoiceNonStreaming
+	extras, err := DecodeWithExtras(data, (*plain)(c))
+	c.Extras = extras
+	return err
 }
 
 type ResponseMessage struct {
 	Role      string         `json:"role"`
 	Content   MessageContent `json:"content"`
 	ToolCalls []ToolCall     `json:"tool_calls,omitempty"`
+	// ReasoningText carries the model's reasoning or reasoning summary.
+	ReasoningText *string `json:"reasoning_text,omitempty"`
+	Extras        Extras  `json:"-"`
+}
+
+func (m ResponseMessage) MarshalJSON() ([]byte, error) {
+	type plain ResponseMessage
+	return EncodeWithExtras(plain(m), m.Extras)
+}
+
+func (m *ResponseMessage) UnmarshalJSON(data []byte) error {
+	type plain ResponseMessage
+	extras, err := DecodeWithExtras(data, (*plain)(m))
+	m.Extras = extras
+	return err
 }
 
 type Delta struct {
-	Content   *string    `json:"content,omitempty"`
-	Role      *string    `json:"role,omitempty"`
-	ToolCalls []ToolCall `json:"tool_calls,omitempty"`
+	Content       *string    `json:"content,omitempty"`
+	Role          *string    `json:"role,omitempty"`
+	ToolCalls     []ToolCall `json:"tool_calls,omitempty"`
+	ReasoningText *string    `json:"reasoning_text,omitempty"`
+	Extras        Extras     `json:"-"`
+}
+
+func (d Delta) MarshalJSON() ([]byte, error) {
+	type plain Delta
+	return EncodeWithExtras(plain(d), d.Extras)
+}
+
+func (d *Delta) UnmarshalJSON(data []byte) error {
+	type plain Delta
+	extras, err := DecodeWithExtras(data, (*plain)(d))
+	d.Extras = extras
+	return err
 }
 
 type ChatCompletionChunk struct {
@@ -223,6 +435,19 @@ type ChatCompletionChunk struct {
 	Choices           []Choice      `json:"choices"`
 	SystemFingerprint *string       `json:"system_fingerprint,omitempty"`
 	Usage             *UsageDetails `json:"usage,omitempty"`
+	Extras            Extras        `json:"-"`
+}
+
+func (c ChatCompletionChunk) MarshalJSON() ([]byte, error) {
+	type plain ChatCompletionChunk
+	return EncodeWithExtras(plain(c), c.Extras)
+}
+
+func (c *ChatCompletionChunk) UnmarshalJSON(data []byte) error {
+	type plain ChatCompletionChunk
+	extras, err := DecodeWithExtras(data, (*plain)(c))
+	c.Extras = extras
+	return err
 }
 
 type UsageDetails struct {
@@ -231,20 +456,53 @@ type UsageDetails struct {
 	TotalTokens             int                      `json:"total_tokens"`
 	PromptTokensDetails     *PromptTokensDetails     `json:"prompt_tokens_details,omitempty"`
 	CompletionTokensDetails *CompletionTokensDetails `json:"completion_tokens_details,omitempty"`
+	Extras                  Extras                   `json:"-"`
+}
+
+func (u UsageDetails) MarshalJSON() ([]byte, error) {
+	type plain UsageDetails
+	return EncodeWithExtras(plain(u), u.Extras)
+}
+
+func (u *UsageDetails) UnmarshalJSON(data []byte) error {
+	type plain UsageDetails
+	extras, err := DecodeWithExtras(data, (*plain)(u))
+	u.Extras = extras
+	return err
 }
 
 type CompletionTokensDetails struct {
-	AcceptedPredictionTokens int `json:"accepted_prediction_tokens"`
-	RejectedPredictionTokens int `json:"rejected_prediction_tokens"`
+	AcceptedPredictionTokens int    `json:"accepted_prediction_tokens"`
+	RejectedPredictionTokens int    `json:"rejected_prediction_tokens"`
+	ReasoningTokens          int    `json:"reasoning_tokens,omitempty"`
+	Extras                   Extras `json:"-"`
+}
+
+func (d CompletionTokensDetails) MarshalJSON() ([]byte, error) {
+	type plain CompletionTokensDetails
+	return EncodeWithExtras(plain(d), d.Extras)
+}
+
+func (d *CompletionTokensDetails) UnmarshalJSON(data []byte) error {
+	type plain CompletionTokensDetails
+	extras, err := DecodeWithExtras(data, (*plain)(d))
+	d.Extras = extras
+	return err
 }
 
 type SSEReader interface {
 	ReadSSE(ctx context.Context, resp *http.Response) (<-chan SSEMessage, error)
 }
 
+// SSEMessage is one event of an upstream stream. ID and Retry carry the
+// stream's last event ID and reconnection time; Err is set on a final
+// message when the stream failed.
 type SSEMessage struct {
 	Event string
 	Data  string
+	ID    string
+	Retry time.Duration
+	Err   error
 }
 
 // CreateChatCompletions proxies the request to the Copilot endpoint.
@@ -257,7 +515,7 @@ func CreateChatCompletions(ctx context.Context, s *state.State, payload ChatComp
 
 	logger.Debug("Calling Copilot chat completions model=%s stream=%v", payload.Model, payload.Stream != nil && *payload.Stream)
 
-	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, io.NopCloser(bytes.NewReader(body)))
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,106 @@
+package copilot
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Extras holds JSON members without a typed field so that new OpenAI
+// parameters and upstream response fields survive a decode/encode round trip.
+type Extras map[string]json.RawMessage
+
+var knownFieldCache sync.Map // reflect.Type -> map[string]bool
+
+// DecodeWithExtras decodes data into v, a pointer to a struct type without
+// custom JSON methods, and returns the members v has no field for. Types in
+// other packages use it to keep their own Extras.
+func DecodeWithExtras(data []byte, v any) (Extras, error) {
+	if err := json.Unmarshal(data, v); err != nil {
+		return nil, err
+	}
+	if bytes.Equal(bytes.TrimSpace(data), []byte("null")) {
+		return nil, nil
+	}
+
+	var members map[string]json.RawMessage
+	if err := json.Unmarshal(data, &members); err != nil {
+		return nil, err
+	}
+	known := knownFields(reflect.TypeOf(v).Elem())
+	for name := range members {
+		if known[strings.ToLower(name)] {
+			delete(members, name)
+		}
+	}
+	if len(members) == 0 {
+		return nil, nil
+	}
+	return members, nil
+}
+
+// EncodeWithExtras encodes v and appends extras that do not collide with a
+// typed field. Typed fields always win.
+func EncodeWithExtras(v any, extras Extras) ([]byte, error) {
+	data, err := json.Marshal(v)
+	if err != nil || len(extras) == 0 {
+		return data, err
+	}
+
+	known := knownFields(reflect.TypeOf(v))
+	names := make([]string, 0, len(extras))
+	for name := range extras {
+		if !known[strings.ToLower(name)] {
+			names = append(names, name)
+		}
+	}
+	if len(names) == 0 {
+		return data, nil
+	}
+	sort.Strings(names)
+
+	var buf bytes.Buffer
+	buf.Write(data[:len(data)-1])
+	empty := len(bytes.TrimSpace(data[1:len(data)-1])) == 0
+	for _, name := range names {
+		value := extras[name]
+		if len(value) == 0 {
+			continue
+		}
+		if !empty {
+			buf.WriteByte(',')
+		}
+		empty = false
+		key, _ := json.Marshal(name)
+		buf.Write(key)
+		buf.WriteByte(':')
+		buf.Write(value)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+func knownFields(t reflect.Type) map[string]bool {
+	if cached, ok := knownFieldCache.Load(t); ok {
+		return cached.(map[string]bool)
+	}
+	known := make(map[string]bool, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := field.Tag.Get("json")
+		if tag == "-" || !field.IsExported() {
+			continue
+		}
+		name, _, _ := strings.Cut(tag, ",")
+		if name == "" {
+			name = field.Name
+		}
+		// encoding/json matches member names case-insensitively.
+		known[strings.ToLower(name)] = true
+	}
+	knownFieldCache.Store(t, known)
+	return known
+}
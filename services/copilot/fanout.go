@@ -0,0 +1,232 @@
+package copilot
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"sync"
+
+	"internal/logger"
+	"internal/rate"
+	"internal/state"
+)
+
+// SupportsMultipleChoices reports whether the upstream honours n>1 for the
+// model. Copilot only forwards n to OpenAI-hosted models; Claude and Gemini
+// either ignore it or reject the request.
+func (m Model) SupportsMultipleChoices() bool {
+	vendor := strings.ToLower(m.Vendor)
+	return strings.Contains(vendor, "openai")
+}
+
+// CreateChatCompletionsFanOut emulates n choices by sending n single-choice
+// requests concurrently. Non-streaming results are merged into one
+// ChatCompletionResponse; streams are multiplexed into one SSE channel with
+// each upstream stream mapped to its own choice index. The caller has passed
+// the rate limit for the first request; each further request waits for its
+// own turn.
+func CreateChatCompletionsFanOut(ctx context.Context, s *state.State, payload ChatCompletionsPayload, n int, httpClient *http.Client, streamer SSEReader) (interface{}, error) {
+	single := payload
+	single.N = nil
+
+	ctx, cancel := context.WithCancel(ctx)
+	results := make([]interface{}, n)
+	errs := make([]error, n)
+
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			if i > 0 {
+				if errs[i] = rate.AwaitRateLimit(ctx, s); errs[i] != nil {
+					cancel()
+					return
+				}
+			}
+			results[i], errs[i] = CreateChatCompletions(ctx, s, single, httpClient, streamer)
+			if errs[i] != nil {
+				cancel()
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	// Report the root cause rather than the cancellations it triggered.
+	var firstErr error
+	for _, err := range errs {
+		if err != nil && (firstErr == nil || errors.Is(firstErr, context.Canceled)) {
+			firstErr = err
+		}
+	}
+	if firstErr != nil {
+		cancel()
+		// Streams that already opened stop on the cancellation; drain them
+		// so their readers close the upstream bodies.
+		for _, result := range results {
+			if stream, ok := result.(<-chan SSEMessage); ok {
+				go func() {
+					for range stream {
+					}
+				}()
+			}
+		}
+		return nil, firstErr
+	}
+
+	logger.Debug("Fanned out %d upstream requests for model %s", n, payload.Model)
+
+	if payload.Stream != nil && *payload.Stream {
+		streams := make([]<-chan SSEMessage, n)
+		for i, result := range results {
+			streams[i], _ = result.(<-chan SSEMessage)
+		}
+		return multiplexStreams(ctx, cancel, streams), nil
+	}
+
+	defer cancel()
+	responses := make([]ChatCompletionResponse, n)
+	for i, result := range results {
+		responses[i], _ = result.(ChatCompletionResponse)
+	}
+	return mergeChatCompletions(responses), nil
+}
+
+func mergeChatCompletions(responses []ChatCompletionResponse) ChatCompletionResponse {
+	merged := responses[0]
+	merged.Choices = nil
+	merged.Usage = nil
+
+	for _, response := range responses {
+		for _, choice := range response.Choices {
+			choice.Index = len(merged.Choices)
+			merged.Choices = append(merged.Choices, choice)
+		}
+		if response.Usage != nil {
+			if merged.Usage == nil {
+				merged.Usage = &ChatUsage{}
+			}
+			addUsage(merged.Usage, response.Usage.PromptTokens, response.Usage.CompletionTokens, response.Usage.TotalTokens, response.Usage.PromptTokensDetails)
+		}
+	}
+	return merged
+}
+
+func addUsage(target *ChatUsage, prompt, completion, total int, details *PromptTokensDetails) {
+	target.PromptTokens += prompt
+	target.CompletionTokens += completion
+	target.TotalTokens += total
+	if details != nil {
+		if target.PromptTokensDetails == nil {
+			target.PromptTokensDetails = &PromptTokensDetails{}
+		}
+		target.PromptTokensDetails.CachedTokens += details.CachedTokens
+		target.PromptTokensDetails.CacheCreationInputTokens += details.CacheCreationInputTokens
+	}
+}
+
+// multiplexStreams merges n upstream streams into one. Stream i becomes choice
+// index i, usage is summed into a final chunk, and a single [DONE] is sent
+// once every upstream stream has finished.
+func multiplexStreams(ctx context.Context, cancel context.CancelFunc, streams []<-chan SSEMessage) <-chan SSEMessage {
+	out := make(chan SSEMessage)
+
+	go func() {
+		defer cancel()
+		defer close(out)
+
+		var (
+			mu      sync.Mutex
+			id      string
+			model   string
+			created int64
+			usage   *ChatUsage
+		)
+
+		send := func(msg SSEMessage) bool {
+			select {
+			case out <- msg:
+				return true
+			case <-ctx.Done():
+				return false
+			}
+		}
+
+		var wg sync.WaitGroup
+		for i, stream := range streams {
+			wg.Add(1)
+			go func(index int, stream <-chan SSEMessage) {
+				defer wg.Done()
+				for msg := range stream {
+					if msg.Err != nil {
+						send(msg)
+						return
+					}
+					if msg.Data == "" || msg.Data == "[DONE]" {
+						continue
+					}
+					var chunk ChatCompletionChunk
+					if err := json.Unmarshal([]byte(msg.Data), &chunk); err != nil {
+						logger.Debug("Dropping undecodable fan-out chunk: %v", err)
+						continue
+					}
+
+					mu.Lock()
+					if id == "" {
+						id, model, created = chunk.ID, chunk.Model, chunk.Created
+					}
+					if chunk.Usage != nil {
+						if usage == nil {
+							usage = &ChatUsage{}
+						}
+						addUsage(usage, chunk.Usage.PromptTokens, chunk.Usage.CompletionTokens, chunk.Usage.TotalTokens, chunk.Usage.PromptTokensDetails)
+					}
+					chunk.ID = id
+					mu.Unlock()
+
+					chunk.Usage = nil
+					if len(chunk.Choices) == 0 {
+						continue
+					}
+					for j := range chunk.Choices {
+						chunk.Choices[j].Index = index
+					}
+					data, err := json.Marshal(chunk)
+					if err != nil {
+						continue
+					}
+					if !send(SSEMessage{Event: msg.Event, Data: string(data)}) {
+						return
+					}
+				}
+			}(i, stream)
+		}
+		wg.Wait()
+
+		if usage != nil {
+			final := ChatCompletionChunk{
+				ID:      id,
+				Object:  "chat.completion.chunk",
+				Created: created,
+				Model:   model,
+				Choices: []Choice{},
+				Usage: &UsageDetails{
+					PromptTokens:        usage.PromptTokens,
+					CompletionTokens:    usage.CompletionTokens,
+					TotalTokens:         usage.TotalTokens,
+					PromptTokensDetails: usage.PromptTokensDetails,
+				},
+			}
+			if data, err := json.Marshal(final); err == nil {
+				if !send(SSEMessage{Data: string(data)}) {
+					return
+				}
+			}
+		}
+		send(SSEMessage{Data: "[DONE]"})
+	}()
+
+	return out
+}
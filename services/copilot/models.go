@@ -27,6 +27,36 @@ type Model struct {
 	Version            string            `json:"version"`
 	Policy             *ModelPolicy      `json:"policy,omitempty"`
 	SupportedEndpoints []string          `json:"supported_endpoints,omitempty"`
+	Billing            *ModelBilling     `json:"billing,omitempty"`
+}
+
+// ModelBilling tells whether requests count against the premium quota and
+// how many premium requests each one costs.
+type ModelBilling struct {
+	IsPremium  bool    `json:"is_premium"`
+	Multiplier float64 `json:"multiplier"`
+}
+
+// IsPremium reports whether the model consumes premium requests.
+func (m Model) IsPremium() bool {
+	return m.Billing != nil && m.Billing.IsPremium
+}
+
+// SupportsEndpoint reports whether endpoint is among the model's supported
+// endpoints.
+func (m Model) SupportsEndpoint(endpoint string) bool {
+	for _, supported := range m.SupportedEndpoints {
+		if supported == endpoint {
+			return true
+		}
+	}
+	return false
+}
+
+// ResponsesOnly reports whether the model is served through /responses but
+// not /chat/completions.
+func (m Model) ResponsesOnly() bool {
+	return m.SupportsEndpoint("/responses") && !m.SupportsEndpoint("/chat/completions")
 }
 
 type ModelPolicy struct {
@@ -4,9 +4,11 @@ import (
 	"context"
 	"encoding/json"
 	"net/http"
+	"sort"
 
 	"internal/api"
 	"internal/errors"
+	"internal/logger"
 	"internal/state"
 )
 
@@ -21,10 +23,91 @@ type QuotaDetail struct {
 	Unlimited        bool    `json:"unlimited"`
 }
 
+// QuotaSnapshots holds the quotas upstream reported; quotas it left out
+// are nil or absent from Other.
 type QuotaSnapshots struct {
-	Chat                QuotaDetail `json:"chat"`
-	Completions         QuotaDetail `json:"completions"`
-	PremiumInteractions QuotaDetail `json:"premium_interactions"`
+	Chat                *QuotaDetail `json:"chat,omitempty"`
+	Completions         *QuotaDetail `json:"completions,omitempty"`
+	PremiumInteractions *QuotaDetail `json:"premium_interactions,omitempty"`
+	// Other holds any quotas beyond the three above, keyed by name.
+	Other map[string]QuotaDetail `json:"-"`
+}
+
+// NamedQuota is a quota snapshot together with its key.
+type NamedQuota struct {
+	Name string
+	QuotaDetail
+}
+
+// All returns every quota present: premium interactions, chat and
+// completions first, then any others by name.
+func (q QuotaSnapshots) All() []NamedQuota {
+	var all []NamedQuota
+	for _, fixed := range []struct {
+		name   string
+		detail *QuotaDetail
+	}{
+		{"premium_interactions", q.PremiumInteractions},
+		{"chat", q.Chat},
+		{"completions", q.Completions},
+	} {
+		if fixed.detail != nil {
+			all = append(all, NamedQuota{Name: fixed.name, QuotaDetail: *fixed.detail})
+		}
+	}
+	names := make([]string, 0, len(q.Other))
+	for name := range q.Other {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		all = append(all, NamedQuota{Name: name, QuotaDetail: q.Other[name]})
+	}
+	return all
+}
+
+func (q *QuotaSnapshots) UnmarshalJSON(data []byte) error {
+	var entries map[string]json.RawMessage
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return err
+	}
+	// A quota in a shape we do not know is left out rather than failing the
+	// whole usage response.
+	raw := make(map[string]QuotaDetail, len(entries))
+	for name, entry := range entries {
+		var detail QuotaDetail
+		if err := json.Unmarshal(entry, &detail); err != nil {
+			logger.Warn("Ignoring quota %q in an unknown shape: %v", name, err)
+			continue
+		}
+		raw[name] = detail
+	}
+	take := func(name string) *QuotaDetail {
+		detail, ok := raw[name]
+		if !ok {
+			return nil
+		}
+		delete(raw, name)
+		return &detail
+	}
+	*q = QuotaSnapshots{
+		Chat:                take("chat"),
+		Completions:         take("completions"),
+		PremiumInteractions: take("premium_interactions"),
+	}
+	if len(raw) > 0 {
+		q.Other = raw
+	}
+	return nil
+}
+
+func (q QuotaSnapshots) MarshalJSON() ([]byte, error) {
+	all := q.All()
+	raw := make(map[string]QuotaDetail, len(all))
+	for _, quota := range all {
+		raw[quota.Name] = quota.QuotaDetail
+	}
+	return json.Marshal(raw)
 }
 
 type CopilotUsageResponse struct {
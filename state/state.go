@@ -1,6 +1,10 @@
 package state
 
-import "sync"
+import (
+	"sync"
+
+	"internal/config"
+)
 
 // State mirrors the TypeScript runtime state object for the proxy.
 type State struct {
@@ -15,7 +19,14 @@ type State struct {
 	RateLimitSeconds  *int
 	LastRequestUnixMs *int64
 	ServerStartUnixMs *int64
-	mutex             sync.RWMutex
+	Config            *config.Config
+	// Usage caches the last *github.CopilotUsageResponse polled in the
+	// background; UsageUnixMs is when it was fetched.
+	Usage       any
+	UsageUnixMs *int64
+	// CopilotTokenFailures counts consecutive failed Copilot token refreshes.
+	CopilotTokenFailures int
+	mutex                sync.RWMutex
 }
 
 // Shared is the singleton application state used across the application.
@@ -24,6 +35,7 @@ var Shared = &State{
 	ManualApprove: false,
 	RateLimitWait: false,
 	ShowToken:     false,
+	Config:        config.Default(),
 }
 
 // Update safely updates state using the provided function.
@@ -0,0 +1,159 @@
+package streaming
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"internal/services/copilot"
+)
+
+// ErrLineTooLong is returned when a line exceeds the parser's limit.
+var ErrLineTooLong = errors.New("sse: line exceeds maximum length")
+
+// Parser decodes a text/event-stream as specified by the WHATWG HTML
+// standard: lines end in CRLF, LF or CR, a leading BOM is skipped, comment
+// lines are ignored, a single space after the colon is removed and an event
+// is dispatched at each blank line. An event that is not terminated by a
+// blank line before the stream ends is discarded.
+type Parser struct {
+	r            *bufio.Reader
+	maxLineBytes int
+
+	started bool
+	skipLF  bool
+	lastID  string
+	retry   time.Duration
+}
+
+// NewParser reads events from r. maxLineBytes bounds a single line; zero
+// means unbounded.
+func NewParser(r io.Reader, maxLineBytes int) *Parser {
+	return &Parser{r: bufio.NewReaderSize(r, 64*1024), maxLineBytes: maxLineBytes}
+}
+
+// Next returns the next event. Event is empty when the stream did not name
+// the event type; ID and Retry carry the last values the stream set. It
+// returns io.EOF once the stream ends.
+func (p *Parser) Next() (copilot.SSEMessage, error) {
+	var (
+		data      strings.Builder
+		hasData   bool
+		eventType string
+	)
+	for {
+		line, err := p.readLine()
+		if err != nil {
+			return copilot.SSEMessage{}, err
+		}
+
+		if len(line) == 0 {
+			if !hasData {
+				eventType = ""
+				continue
+			}
+			return copilot.SSEMessage{
+				Event: eventType,
+				Data:  strings.TrimSuffix(data.String(), "\n"),
+				ID:    p.lastID,
+				Retry: p.retry,
+			}, nil
+		}
+		if line[0] == ':' {
+			continue
+		}
+
+		field, value := line, []byte(nil)
+		if i := bytes.IndexByte(line, ':'); i >= 0 {
+			field, value = line[:i], line[i+1:]
+			if len(value) > 0 && value[0] == ' ' {
+				value = value[1:]
+			}
+		}
+
+		switch string(field) {
+		case "event":
+			eventType = string(value)
+		case "data":
+			data.Write(value)
+			data.WriteByte('\n')
+			hasData = true
+		case "id":
+			if bytes.IndexByte(value, 0) < 0 {
+				p.lastID = string(value)
+			}
+		case "retry":
+			if isDigits(value) {
+				if ms, err := strconv.ParseInt(string(value), 10, 64); err == nil {
+					p.retry = time.Duration(ms) * time.Millisecond
+				}
+			}
+		}
+	}
+}
+
+// readLine returns the next line without its terminator. A CR is treated as
+// a complete line ending immediately so CR-framed streams are not delayed;
+// an LF that follows it is skipped on the next read.
+func (p *Parser) readLine() ([]byte, error) {
+	if !p.started {
+		p.started = true
+		// Wait for enough bytes to recognise a BOM. Fewer are only returned
+		// when the stream ended or failed, which the loop below reports.
+		if buf, _ := p.r.Peek(3); bytes.HasPrefix(buf, []byte("\xEF\xBB\xBF")) {
+			p.r.Discard(3)
+		}
+	}
+
+	var line []byte
+	for {
+		n := p.r.Buffered()
+		if n == 0 {
+			n = 1
+		}
+		buf, err := p.r.Peek(n)
+		if len(buf) > 0 {
+			if p.skipLF {
+				p.skipLF = false
+				if buf[0] == '\n' {
+					p.r.Discard(1)
+					continue
+				}
+			}
+			if i := bytes.IndexAny(buf, "\r\n"); i >= 0 {
+				line = append(line, buf[:i]...)
+				p.skipLF = buf[i] == '\r'
+				p.r.Discard(i + 1)
+				if p.maxLineBytes > 0 && len(line) > p.maxLineBytes {
+					return nil, ErrLineTooLong
+				}
+				return line, nil
+			}
+			line = append(line, buf...)
+			p.r.Discard(len(buf))
+			if p.maxLineBytes > 0 && len(line) > p.maxLineBytes {
+				return nil, ErrLineTooLong
+			}
+		}
+		if err != nil {
+			// A final line without a terminator cannot complete an event.
+			return nil, err
+		}
+	}
+}
+
+func isDigits(value []byte) bool {
+	if len(value) == 0 {
+		return false
+	}
+	for _, b := range value {
+		if b < '0' || b > '9' {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,189 @@
+package streaming
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"testing/iotest"
+	"time"
+
+	"internal/services/copilot"
+)
+
+func parseAll(t *testing.T, r io.Reader, maxLineBytes int) ([]copilot.SSEMessage, error) {
+	t.Helper()
+	parser := NewParser(r, maxLineBytes)
+	var events []copilot.SSEMessage
+	for {
+		msg, err := parser.Next()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return events, nil
+			}
+			return events, err
+		}
+		events = append(events, msg)
+	}
+}
+
+func TestParserConformance(t *testing.T) {
+	tests := []struct {
+		name   string
+		stream string
+		want   []copilot.SSEMessage
+	}{
+		{
+			name:   "LF",
+			stream: "data: a\n\ndata: b\n\n",
+			want:   []copilot.SSEMessage{{Data: "a"}, {Data: "b"}},
+		},
+		{
+			name:   "CRLF",
+			stream: "data: a\r\n\r\ndata: b\r\n\r\n",
+			want:   []copilot.SSEMessage{{Data: "a"}, {Data: "b"}},
+		},
+		{
+			name:   "CR only",
+			stream: "data: a\r\rdata: b\r\r",
+			want:   []copilot.SSEMessage{{Data: "a"}, {Data: "b"}},
+		},
+		{
+			name:   "mixed line endings",
+			stream: "data: a\r\ndata: b\rdata: c\n\n",
+			want:   []copilot.SSEMessage{{Data: "a\nb\nc"}},
+		},
+		{
+			name:   "BOM",
+			stream: "\xEF\xBB\xBFdata: a\n\n",
+			want:   []copilot.SSEMessage{{Data: "a"}},
+		},
+		{
+			name:   "only the first BOM is skipped",
+			stream: "\xEF\xBB\xBF\xEF\xBB\xBFdata: a\n\ndata: b\n\n",
+			want:   []copilot.SSEMessage{{Data: "b"}},
+		},
+		{
+			name:   "comments",
+			stream: ": keepalive\n:\ndata: a\n: inside\n\n",
+			want:   []copilot.SSEMessage{{Data: "a"}},
+		},
+		{
+			name:   "single leading space stripped",
+			stream: "data:a\n\ndata: b\n\ndata:  c\n\n",
+			want:   []copilot.SSEMessage{{Data: "a"}, {Data: "b"}, {Data: " c"}},
+		},
+		{
+			name:   "multi-line data",
+			stream: "data: first\ndata\ndata: third\n\n",
+			want:   []copilot.SSEMessage{{Data: "first\n\nthird"}},
+		},
+		{
+			name:   "event type resets after dispatch",
+			stream: "event: ping\ndata: a\n\ndata: b\n\n",
+			want:   []copilot.SSEMessage{{Event: "ping", Data: "a"}, {Data: "b"}},
+		},
+		{
+			name:   "event without data is not dispatched",
+			stream: "event: ping\n\ndata: a\n\n",
+			want:   []copilot.SSEMessage{{Data: "a"}},
+		},
+		{
+			name:   "id persists across events",
+			stream: "id: 1\ndata: a\n\ndata: b\n\nid\ndata: c\n\n",
+			want:   []copilot.SSEMessage{{ID: "1", Data: "a"}, {ID: "1", Data: "b"}, {Data: "c"}},
+		},
+		{
+			name:   "id containing NUL is ignored",
+			stream: "id: 1\ndata: a\n\nid: 2\x003\ndata: b\n\n",
+			want:   []copilot.SSEMessage{{ID: "1", Data: "a"}, {ID: "1", Data: "b"}},
+		},
+		{
+			name:   "retry",
+			stream: "retry: 1500\ndata: a\n\nretry: 1.5\ndata: b\n\n",
+			want:   []copilot.SSEMessage{{Data: "a", Retry: 1500 * time.Millisecond}, {Data: "b", Retry: 1500 * time.Millisecond}},
+		},
+		{
+			name:   "unknown fields are ignored",
+			stream: "foo: bar\ndata: a\n\n",
+			want:   []copilot.SSEMessage{{Data: "a"}},
+		},
+		{
+			name:   "unterminated event is discarded",
+			stream: "data: a\n\ndata: b\n",
+			want:   []copilot.SSEMessage{{Data: "a"}},
+		},
+		{
+			name:   "empty stream",
+			stream: "",
+			want:   nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, reader := range []struct {
+				name string
+				r    io.Reader
+			}{
+				{"whole", strings.NewReader(tt.stream)},
+				{"one byte at a time", iotest.OneByteReader(strings.NewReader(tt.stream))},
+			} {
+				got, err := parseAll(t, reader.r, 0)
+				if err != nil {
+					t.Fatalf("%s: unexpected error: %v", reader.name, err)
+				}
+				if len(got) != len(tt.want) {
+					t.Fatalf("%s: got %d events %#v, want %d %#v", reader.name, len(got), got, len(tt.want), tt.want)
+				}
+				for i := range got {
+					if got[i] != tt.want[i] {
+						t.Errorf("%s: event %d = %#v, want %#v", reader.name, i, got[i], tt.want[i])
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestParserLineTooLong(t *testing.T) {
+	_, err := parseAll(t, strings.NewReader("data: "+strings.Repeat("x", 100)+"\n\n"), 32)
+	if !errors.Is(err, ErrLineTooLong) {
+		t.Fatalf("got %v, want ErrLineTooLong", err)
+	}
+}
+
+func FuzzParser(f *testing.F) {
+	for _, seed := range []string{
+		"data: a\n\n",
+		"data: a\r\n\r\n",
+		"data: a\r\r",
+		"\xEF\xBB\xBFdata: a\n\n",
+		": comment\nevent: x\nid: 1\nretry: 10\ndata: a\ndata: b\n\n",
+		"data\n\n",
+		"\r\n\r",
+	} {
+		f.Add(seed)
+	}
+	f.Fuzz(func(t *testing.T, stream string) {
+		whole, errWhole := parseAll(t, strings.NewReader(stream), 0)
+		split, errSplit := parseAll(t, iotest.OneByteReader(strings.NewReader(stream)), 0)
+		if (errWhole == nil) != (errSplit == nil) {
+			t.Fatalf("errors differ: %v vs %v", errWhole, errSplit)
+		}
+		if len(whole) != len(split) {
+			t.Fatalf("event counts differ: %d vs %d", len(whole), len(split))
+		}
+		for i := range whole {
+			if whole[i] != split[i] {
+				t.Fatalf("event %d differs: %#v vs %#v", i, whole[i], split[i])
+			}
+			if strings.ContainsAny(whole[i].Event, "\r\n") || strings.ContainsAny(whole[i].ID, "\r\n\x00") {
+				t.Fatalf("event %d has a line break in a field: %#v", i, whole[i])
+			}
+			if strings.ContainsRune(whole[i].Data, '\r') {
+				t.Fatalf("event %d data contains CR: %q", i, whole[i].Data)
+			}
+		}
+	})
+}
@@ -1,74 +1,47 @@
 package streaming
 
 import (
-	"bufio"
 	"context"
+	"errors"
+	"io"
 	"net/http"
-	"strings"
 
 	"internal/services/copilot"
 )
 
-type Reader struct{}
+// Reader turns an upstream text/event-stream response into SSE messages.
+// MaxLineBytes bounds a single line; zero means unbounded.
+type Reader struct {
+	MaxLineBytes int
+}
 
-func (Reader) ReadSSE(ctx context.Context, resp *http.Response) (<-chan copilot.SSEMessage, error) {
+// ReadSSE parses resp in the background. A read or framing error is sent as
+// a final message with Err set before the channel closes.
+func (r Reader) ReadSSE(ctx context.Context, resp *http.Response) (<-chan copilot.SSEMessage, error) {
 	ch := make(chan copilot.SSEMessage)
 
 	go func() {
 		defer resp.Body.Close()
 		defer close(ch)
 
-		scanner := bufio.NewScanner(resp.Body)
-		scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
-
-		var (
-			currentEvent string
-			dataBuilder  strings.Builder
-		)
-
-		flush := func() {
-			if dataBuilder.Len() == 0 {
-				return
+		parser := NewParser(resp.Body, r.MaxLineBytes)
+		for {
+			msg, err := parser.Next()
+			if err != nil {
+				if errors.Is(err, io.EOF) || ctx.Err() != nil {
+					return
+				}
+				msg = copilot.SSEMessage{Err: err}
 			}
 			select {
 			case <-ctx.Done():
 				return
-			case ch <- copilot.SSEMessage{
-				Event: currentEvent,
-				Data:  dataBuilder.String(),
-			}:
+			case ch <- msg:
 			}
-			dataBuilder.Reset()
-			currentEvent = ""
-		}
-
-		for scanner.Scan() {
-			select {
-			case <-ctx.Done():
+			if msg.Err != nil {
 				return
-			default:
-			}
-
-			line := scanner.Text()
-			if line == "" {
-				flush()
-				continue
-			}
-
-			if strings.HasPrefix(line, "event:") {
-				currentEvent = strings.TrimSpace(line[len("event:"):])
-				continue
-			}
-
-			if strings.HasPrefix(line, "data:") {
-				if dataBuilder.Len() > 0 {
-					dataBuilder.WriteByte('\n')
-				}
-				dataBuilder.WriteString(strings.TrimSpace(line[len("data:"):]))
 			}
 		}
-
-		flush()
 	}()
 
 	return ch, nil
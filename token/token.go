@@ -111,8 +111,14 @@ func SetupCopilotToken(ctx context.Context, s *state.State, client *http.Client)
 				resp, err := github.GetCopilotToken(context.Background(), s, client)
 				if err != nil {
 					logger.Error("Failed to refresh Copilot token: %v", err)
+					s.Update(func(st *state.State) {
+						st.CopilotTokenFailures++
+					})
 					continue
 				}
+				s.Update(func(st *state.State) {
+					st.CopilotTokenFailures = 0
+				})
 				updateCopilotToken(s, resp.Token)
 				logger.Debug("Copilot token refreshed")
 			}
@@ -0,0 +1,98 @@
+package upstream
+
+import (
+	"math/rand/v2"
+	"sync"
+	"time"
+)
+
+// outcome is how an attempt counts towards its circuit.
+type outcome int
+
+const (
+	succeeded outcome = iota
+	failed
+	// neutral attempts, such as cancelled requests or 429s, neither close
+	// nor trip the circuit.
+	neutral
+)
+
+type circuit struct {
+	failures  int
+	openUntil time.Time
+	probing   bool
+}
+
+// breakers tracks one circuit per upstream host and model. A circuit opens
+// after threshold consecutive failures and rejects requests for a jittered
+// cooldown; afterwards a single probe is let through, which closes the
+// circuit on success or reopens it on failure.
+type breakers struct {
+	threshold int
+	cooldown  time.Duration
+
+	mu       sync.Mutex
+	circuits map[string]*circuit
+}
+
+func newBreakers(threshold int, cooldown time.Duration) *breakers {
+	return &breakers{threshold: threshold, cooldown: cooldown, circuits: make(map[string]*circuit)}
+}
+
+// allow reports whether a request for key may be sent and, if not, how long
+// the circuit stays open.
+func (b *breakers) allow(key string) (bool, time.Duration) {
+	if b.threshold <= 0 {
+		return true, 0
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	c, ok := b.circuits[key]
+	if !ok || c.failures < b.threshold {
+		return true, 0
+	}
+	if wait := time.Until(c.openUntil); wait > 0 {
+		return false, wait
+	}
+	if c.probing {
+		return false, b.cooldown
+	}
+	c.probing = true
+	return true, 0
+}
+
+// record updates the circuit for key with the result of an attempt and
+// reports whether the circuit has just opened.
+func (b *breakers) record(key string, result outcome) bool {
+	if b.threshold <= 0 {
+		return false
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	c, ok := b.circuits[key]
+	if !ok {
+		c = &circuit{}
+		b.circuits[key] = c
+	}
+	wasProbing := c.probing
+	c.probing = false
+
+	switch result {
+	case succeeded:
+		delete(b.circuits, key)
+	case failed:
+		c.failures++
+		if wasProbing || c.failures == b.threshold {
+			c.openUntil = time.Now().Add(jitter(b.cooldown))
+			return true
+		}
+	}
+	return false
+}
+
+// jitter spreads d over [0.8d, 1.2d) so clients do not probe in lockstep.
+func jitter(d time.Duration) time.Duration {
+	return time.Duration(float64(d) * (0.8 + 0.4*rand.Float64()))
+}
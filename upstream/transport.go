@@ -0,0 +1,195 @@
+package upstream
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"internal/config"
+	appErr "internal/errors"
+	"internal/logger"
+)
+
+// maxRetryAfter caps how long a 429 may ask us to wait before the retry is
+// abandoned and the response handed to the client instead.
+const maxRetryAfter = 30 * time.Second
+
+// Transport retries Copilot API requests that failed before producing a
+// usable response and fails fast while an upstream host and model keep
+// failing. Requests to other hosts are passed to Base unchanged.
+// Retries only happen before a response is returned, so a streamed body is
+// never replayed.
+type Transport struct {
+	Base http.RoundTripper
+
+	retries  int
+	backoff  time.Duration
+	breakers *breakers
+}
+
+// NewTransport wraps base with the retry and circuit breaker settings in cfg.
+func NewTransport(base http.RoundTripper, cfg config.UpstreamConfig) *Transport {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	t := &Transport{
+		Base:    base,
+		backoff: time.Duration(cfg.RetryBackoffMillis) * time.Millisecond,
+	}
+	if cfg.Retries != nil {
+		t.retries = *cfg.Retries
+	}
+	threshold := 0
+	if cfg.BreakerThreshold != nil {
+		threshold = *cfg.BreakerThreshold
+	}
+	t.breakers = newBreakers(threshold, time.Duration(cfg.BreakerCooldownSeconds)*time.Second)
+	return t
+}
+
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if !copilotAPI(req) {
+		return t.Base.RoundTrip(req)
+	}
+	key := circuitKey(req)
+	retries := t.retries
+	if req.Body != nil && req.Body != http.NoBody && req.GetBody == nil {
+		retries = 0
+	}
+
+	for attempt := 0; ; attempt++ {
+		if ok, wait := t.breakers.allow(key); !ok {
+			return circuitOpenResponse(req, key, wait), nil
+		}
+
+		attemptReq := req
+		if attempt > 0 && req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			attemptReq = req.Clone(req.Context())
+			attemptReq.Body = body
+		}
+
+		resp, err := t.Base.RoundTrip(attemptReq)
+		result, delay, retryable := t.classify(req, resp, err, attempt)
+		if t.breakers.record(key, result) {
+			logger.Warn("Upstream %s is failing, circuit opened", key)
+		}
+		if !retryable || attempt >= retries {
+			return resp, err
+		}
+
+		if err != nil {
+			logger.Warn("Upstream %s request failed (%v), retrying in %s", key, err, delay)
+		} else {
+			logger.Warn("Upstream %s returned %d, retrying in %s", key, resp.StatusCode, delay)
+			io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+			resp.Body.Close()
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-req.Context().Done():
+			timer.Stop()
+			return nil, req.Context().Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// classify decides how an attempt counts towards the circuit and whether,
+// and after how long, it may be retried.
+func (t *Transport) classify(req *http.Request, resp *http.Response, err error, attempt int) (outcome, time.Duration, bool) {
+	if err != nil {
+		if req.Context().Err() != nil {
+			return neutral, 0, false
+		}
+		return failed, t.backoffDelay(attempt), true
+	}
+	switch {
+	case resp.StatusCode == http.StatusTooManyRequests:
+		wait, ok := retryAfter(resp)
+		return neutral, wait, ok && wait <= maxRetryAfter
+	case resp.StatusCode >= 500:
+		delay := t.backoffDelay(attempt)
+		if wait, ok := retryAfter(resp); ok && wait > delay && wait <= maxRetryAfter {
+			delay = wait
+		}
+		return failed, delay, true
+	}
+	return succeeded, 0, false
+}
+
+// backoffDelay doubles the base delay per attempt and picks a random point
+// in its upper half.
+func (t *Transport) backoffDelay(attempt int) time.Duration {
+	d := float64(t.backoff) * math.Pow(2, float64(attempt))
+	return time.Duration(d/2 + rand.Float64()*d/2)
+}
+
+func retryAfter(resp *http.Response) (time.Duration, bool) {
+	value := resp.Header.Get("Retry-After")
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+	if at, err := http.ParseTime(value); err == nil {
+		return max(time.Until(at), 0), true
+	}
+	return 0, false
+}
+
+// copilotAPI reports whether req goes to the Copilot API. GitHub (OAuth,
+// Copilot token, usage) and VS Code version requests pass straight through,
+// so their failures never open a circuit that blocks token refresh.
+func copilotAPI(req *http.Request) bool {
+	host := req.URL.Hostname()
+	return host == "githubcopilot.com" || strings.HasSuffix(host, ".githubcopilot.com")
+}
+
+// circuitKey identifies the circuit a request belongs to: its host and, for
+// JSON requests, the model it targets.
+func circuitKey(req *http.Request) string {
+	key := req.URL.Host
+	if req.GetBody == nil {
+		return key
+	}
+	body, err := req.GetBody()
+	if err != nil {
+		return key
+	}
+	defer body.Close()
+	var payload struct {
+		Model string `json:"model"`
+	}
+	if json.NewDecoder(body).Decode(&payload) == nil && payload.Model != "" {
+		key += "/" + payload.Model
+	}
+	return key
+}
+
+// circuitOpenResponse is returned instead of contacting an upstream whose
+// circuit is open.
+func circuitOpenResponse(req *http.Request, key string, wait time.Duration) *http.Response {
+	seconds := int(math.Ceil(wait.Seconds()))
+	resp := appErr.NewJSONResponse(http.StatusServiceUnavailable, map[string]any{
+		"error": map[string]any{
+			"message": fmt.Sprintf("Upstream %s is unavailable; retry in %ds.", key, seconds),
+			"type":    "server_error",
+			"code":    "circuit_open",
+		},
+	})
+	resp.Header.Set("Retry-After", strconv.Itoa(seconds))
+	resp.Request = req
+	return resp
+}
@@ -0,0 +1,115 @@
+package usage
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"internal/logger"
+	"internal/services/github"
+	"internal/state"
+)
+
+// QuotaPoint is one quota in a snapshot.
+type QuotaPoint struct {
+	Entitlement float64 `json:"entitlement,omitempty"`
+	Remaining   float64 `json:"remaining,omitempty"`
+	Unlimited   bool    `json:"unlimited,omitempty"`
+}
+
+// Snapshot records every quota at one point in time.
+type Snapshot struct {
+	// Timestamp is in Unix milliseconds.
+	Timestamp int64                 `json:"ts"`
+	ResetDate string                `json:"reset,omitempty"`
+	Quotas    map[string]QuotaPoint `json:"quotas"`
+}
+
+// Time returns when the snapshot was taken.
+func (s Snapshot) Time() time.Time {
+	return time.UnixMilli(s.Timestamp)
+}
+
+// NewSnapshot captures the quotas of result as of at.
+func NewSnapshot(result *github.CopilotUsageResponse, at time.Time) Snapshot {
+	snapshot := Snapshot{
+		Timestamp: at.UnixMilli(),
+		ResetDate: result.QuotaResetDate,
+		Quotas:    make(map[string]QuotaPoint),
+	}
+	for _, quota := range result.QuotaSnapshots.All() {
+		snapshot.Quotas[quota.Name] = QuotaPoint{
+			Entitlement: quota.Entitlement,
+			Remaining:   quota.Remaining,
+			Unlimited:   quota.Unlimited,
+		}
+	}
+	return snapshot
+}
+
+// History is an append-only JSON Lines file of quota snapshots.
+type History struct {
+	log jsonLog[Snapshot]
+}
+
+func NewHistory(path string) *History {
+	return &History{log: jsonLog[Snapshot]{path: path}}
+}
+
+// Append writes snapshot as one line at the end of the history.
+func (h *History) Append(snapshot Snapshot) error {
+	return h.log.append(snapshot)
+}
+
+// Read returns the snapshots taken at or after since, oldest first; a zero
+// since returns all of them. Unreadable lines are skipped.
+func (h *History) Read(since time.Time) ([]Snapshot, error) {
+	return h.log.read(func(snapshot Snapshot) bool {
+		return since.IsZero() || !snapshot.Time().Before(since)
+	})
+}
+
+// StartSnapshots appends Copilot usage to h every interval. Usage cached
+// by polling is used when it is newer than the previous snapshot;
+// otherwise it is fetched. The returned function stops snapshotting.
+func StartSnapshots(ctx context.Context, s *state.State, client *http.Client, h *History, interval time.Duration) context.CancelFunc {
+	ctx, cancel := context.WithCancel(ctx)
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		var last int64
+		for {
+			if taken, ok := takeSnapshot(ctx, s, client, h, last); ok {
+				last = taken
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+	return cancel
+}
+
+func takeSnapshot(ctx context.Context, s *state.State, client *http.Client, h *History, last int64) (int64, bool) {
+	var fetched int64
+	s.Read(func(st *state.State) {
+		if st.UsageUnixMs != nil {
+			fetched = *st.UsageUnixMs
+		}
+	})
+	result := Cached(s)
+	if result == nil || fetched <= last {
+		var err error
+		if result, err = Refresh(ctx, s, client); err != nil {
+			return 0, false
+		}
+		fetched = time.Now().UnixMilli()
+	}
+	if err := h.Append(NewSnapshot(result, time.UnixMilli(fetched))); err != nil {
+		logger.Error("Failed to write usage history: %v", err)
+		return 0, false
+	}
+	return fetched, true
+}
@@ -0,0 +1,39 @@
+package usage
+
+import (
+	"sync"
+
+	"internal/fsutil"
+)
+
+// jsonLog is an append-only JSON Lines file of records. Every record is
+// written with one O_APPEND write, so reads take no lock: they only ever see
+// whole lines, apart from a line still being written, which does not decode
+// and is skipped.
+type jsonLog[T any] struct {
+	path string
+	mu   sync.Mutex
+}
+
+// append writes record as one line at the end of the log.
+func (l *jsonLog[T]) append(record T) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return fsutil.AppendJSONLine(l.path, record)
+}
+
+// read returns the records for which keep is true, oldest first. Unreadable
+// lines are skipped.
+func (l *jsonLog[T]) read(keep func(T) bool) ([]T, error) {
+	records, err := fsutil.ReadJSONLines[T](l.path)
+	if err != nil {
+		return nil, err
+	}
+	kept := records[:0]
+	for _, record := range records {
+		if keep(record) {
+			kept = append(kept, record)
+		}
+	}
+	return kept, nil
+}
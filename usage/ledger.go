@@ -0,0 +1,46 @@
+package usage
+
+import "time"
+
+// Entry is one completed request in the local usage ledger.
+type Entry struct {
+	// Timestamp is in Unix milliseconds.
+	Timestamp        int64  `json:"ts"`
+	Key              string `json:"key,omitempty"`
+	Model            string `json:"model,omitempty"`
+	Route            string `json:"route"`
+	Initiator        string `json:"initiator,omitempty"`
+	PromptTokens     int    `json:"prompt,omitempty"`
+	CompletionTokens int    `json:"completion,omitempty"`
+	CachedTokens     int    `json:"cached,omitempty"`
+	LatencyMs        int64  `json:"ms"`
+	Status           int    `json:"status"`
+}
+
+// Time returns when the request completed.
+func (e Entry) Time() time.Time {
+	return time.UnixMilli(e.Timestamp)
+}
+
+// Ledger is an append-only JSON Lines file of completed requests.
+type Ledger struct {
+	log jsonLog[Entry]
+}
+
+func NewLedger(path string) *Ledger {
+	return &Ledger{log: jsonLog[Entry]{path: path}}
+}
+
+// Append writes entry as one line at the end of the ledger.
+func (l *Ledger) Append(entry Entry) error {
+	return l.log.append(entry)
+}
+
+// Read returns the entries completed in [since, until); zero times leave
+// that side open. Unreadable lines are skipped.
+func (l *Ledger) Read(since, until time.Time) ([]Entry, error) {
+	return l.log.read(func(entry Entry) bool {
+		at := entry.Time()
+		return (since.IsZero() || !at.Before(since)) && (until.IsZero() || at.Before(until))
+	})
+}
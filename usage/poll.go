@@ -0,0 +1,65 @@
+package usage
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"internal/logger"
+	"internal/services/github"
+	"internal/state"
+)
+
+// StartPolling fetches Copilot usage right away and then every interval,
+// caching the result in state. Failures keep the previous value. The
+// returned function stops polling.
+func StartPolling(ctx context.Context, s *state.State, client *http.Client, interval time.Duration) context.CancelFunc {
+	ctx, cancel := context.WithCancel(ctx)
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			Refresh(ctx, s, client)
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+	return cancel
+}
+
+// Refresh fetches Copilot usage once and caches it.
+func Refresh(ctx context.Context, s *state.State, client *http.Client) (*github.CopilotUsageResponse, error) {
+	result, err := github.GetCopilotUsage(ctx, s, client)
+	if err != nil {
+		if ctx.Err() == nil {
+			logger.Warn("Failed to refresh Copilot usage: %v", err)
+		}
+		return nil, err
+	}
+	Store(s, result)
+	return result, nil
+}
+
+// Store caches a usage response fetched elsewhere.
+func Store(s *state.State, result *github.CopilotUsageResponse) {
+	now := time.Now().UnixMilli()
+	s.Update(func(st *state.State) {
+		st.Usage = result
+		st.UsageUnixMs = &now
+	})
+	if premium := result.QuotaSnapshots.PremiumInteractions; premium != nil && !premium.Unlimited {
+		logger.Debug("Premium requests remaining: %.0f/%.0f", premium.Remaining, premium.Entitlement)
+	}
+}
+
+// Cached returns the last usage fetched, or nil if none was.
+func Cached(s *state.State) *github.CopilotUsageResponse {
+	var cached *github.CopilotUsageResponse
+	s.Read(func(st *state.State) {
+		cached, _ = st.Usage.(*github.CopilotUsageResponse)
+	})
+	return cached
+}
@@ -0,0 +1,148 @@
+package usage
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// Dimensions a report can be grouped by.
+const (
+	ByDay   = "day"
+	ByKey   = "key"
+	ByModel = "model"
+)
+
+// Row aggregates the entries sharing a day, key and model. Dimensions the
+// report is not grouped by are empty.
+type Row struct {
+	Day              string `json:"day,omitempty"`
+	Key              string `json:"key,omitempty"`
+	Model            string `json:"model,omitempty"`
+	Requests         int    `json:"requests"`
+	Errors           int    `json:"errors"`
+	PromptTokens     int    `json:"prompt_tokens"`
+	CompletionTokens int    `json:"completion_tokens"`
+	CachedTokens     int    `json:"cached_tokens"`
+	AvgLatencyMs     int64  `json:"avg_latency_ms"`
+}
+
+// ParseGroupBy validates a comma separated list of dimensions.
+func ParseGroupBy(value string) ([]string, error) {
+	if strings.TrimSpace(value) == "" {
+		return []string{ByDay, ByKey, ByModel}, nil
+	}
+	var groupBy []string
+	for _, part := range strings.Split(value, ",") {
+		part = strings.TrimSpace(part)
+		switch part {
+		case ByDay, ByKey, ByModel:
+			groupBy = append(groupBy, part)
+		default:
+			return nil, fmt.Errorf("unknown group %q; use day, key or model", part)
+		}
+	}
+	return groupBy, nil
+}
+
+// Aggregate sums entries per combination of the groupBy dimensions. Days
+// are taken in loc. Rows are ordered by day, key and model.
+func Aggregate(entries []Entry, groupBy []string, loc *time.Location) []Row {
+	has := func(dimension string) bool {
+		for _, candidate := range groupBy {
+			if candidate == dimension {
+				return true
+			}
+		}
+		return false
+	}
+
+	rows := make(map[[3]string]*Row)
+	latency := make(map[[3]string]int64)
+	for _, entry := range entries {
+		var id [3]string
+		if has(ByDay) {
+			id[0] = entry.Time().In(loc).Format("2006-01-02")
+		}
+		if has(ByKey) {
+			id[1] = entry.Key
+		}
+		if has(ByModel) {
+			id[2] = entry.Model
+		}
+		row, ok := rows[id]
+		if !ok {
+			row = &Row{Day: id[0], Key: id[1], Model: id[2]}
+			rows[id] = row
+		}
+		row.Requests++
+		if entry.Status >= 400 {
+			row.Errors++
+		}
+		row.PromptTokens += entry.PromptTokens
+		row.CompletionTokens += entry.CompletionTokens
+		row.CachedTokens += entry.CachedTokens
+		latency[id] += entry.LatencyMs
+	}
+
+	result := make([]Row, 0, len(rows))
+	for id, row := range rows {
+		row.AvgLatencyMs = latency[id] / int64(row.Requests)
+		result = append(result, *row)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		a, b := result[i], result[j]
+		if a.Day != b.Day {
+			return a.Day < b.Day
+		}
+		if a.Key != b.Key {
+			return a.Key < b.Key
+		}
+		return a.Model < b.Model
+	})
+	return result
+}
+
+// WriteTable renders rows as an aligned text table with the grouped columns
+// first.
+func WriteTable(w io.Writer, rows []Row, groupBy []string) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	var header []string
+	for _, dimension := range groupBy {
+		header = append(header, strings.ToUpper(dimension))
+	}
+	header = append(header, "REQUESTS", "ERRORS", "PROMPT", "COMPLETION", "CACHED", "AVG MS")
+	fmt.Fprintln(tw, strings.Join(header, "\t")+"\t")
+
+	for _, row := range rows {
+		var cells []string
+		for _, dimension := range groupBy {
+			value := map[string]string{ByDay: row.Day, ByKey: row.Key, ByModel: row.Model}[dimension]
+			if value == "" {
+				value = "-"
+			}
+			cells = append(cells, value)
+		}
+		cells = append(cells,
+			fmt.Sprint(row.Requests),
+			fmt.Sprint(row.Errors),
+			fmt.Sprint(row.PromptTokens),
+			fmt.Sprint(row.CompletionTokens),
+			fmt.Sprint(row.CachedTokens),
+			fmt.Sprint(row.AvgLatencyMs),
+		)
+		fmt.Fprintln(tw, strings.Join(cells, "\t")+"\t")
+	}
+	return tw.Flush()
+}
+
+// ParseDay parses a YYYY-MM-DD date in loc; an empty value is the zero time.
+func ParseDay(value string, loc *time.Location) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	return time.ParseInLocation("2006-01-02", value, loc)
+}
@@ -0,0 +1,243 @@
+package usage
+
+import (
+	"cmp"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strings"
+	"time"
+)
+
+// burnWindow is how far back the daily burn rate is averaged.
+const burnWindow = 7 * 24 * time.Hour
+
+// MaxTrendDays bounds how many days of usage a trend reports.
+const MaxTrendDays = 366
+
+// DayUsage is how much of a quota was used on one day.
+type DayUsage struct {
+	Day  string  `json:"day"`
+	Used float64 `json:"used"`
+}
+
+// Trend summarizes how fast a quota is used and whether it lasts until it
+// resets.
+type Trend struct {
+	Quota       string  `json:"quota"`
+	Entitlement float64 `json:"entitlement"`
+	Remaining   float64 `json:"remaining"`
+	ResetDate   string  `json:"reset_date,omitempty"`
+	// DailyBurn is the average use per day over the last seven days.
+	DailyBurn float64    `json:"daily_burn"`
+	Days      []DayUsage `json:"days"`
+	Sparkline string     `json:"sparkline"`
+	// ProjectedExhaustion is the day the quota runs out at DailyBurn; it is
+	// empty when nothing is being used.
+	ProjectedExhaustion string `json:"projected_exhaustion,omitempty"`
+	ExhaustsBeforeReset bool   `json:"exhausts_before_reset"`
+	// DaysToSpare is how many days the quota is projected to outlast (or,
+	// when negative, fall short of) the reset date.
+	DaysToSpare *float64  `json:"days_to_spare,omitempty"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// Trends computes a trend for every limited quota in the latest snapshot,
+// with per-day usage for the days ending with now. Usage is the drop in
+// remaining between consecutive snapshots; drops across a quota reset are
+// ignored.
+func Trends(snapshots []Snapshot, days int, now time.Time, loc *time.Location) []Trend {
+	if len(snapshots) == 0 {
+		return []Trend{}
+	}
+	latest := snapshots[len(snapshots)-1]
+	var names []string
+	for name, point := range latest.Quotas {
+		if !point.Unlimited {
+			names = append(names, name)
+		}
+	}
+	sort.Slice(names, func(i, j int) bool {
+		if ri, rj := quotaRank(names[i]), quotaRank(names[j]); ri != rj {
+			return ri < rj
+		}
+		return names[i] < names[j]
+	})
+
+	trends := make([]Trend, 0, len(names))
+	for _, name := range names {
+		trends = append(trends, trend(name, snapshots, days, now, loc))
+	}
+	return trends
+}
+
+func trend(name string, snapshots []Snapshot, days int, now time.Time, loc *time.Location) Trend {
+	latest := snapshots[len(snapshots)-1]
+	point := latest.Quotas[name]
+	t := Trend{
+		Quota:       name,
+		Entitlement: point.Entitlement,
+		Remaining:   point.Remaining,
+		ResetDate:   latest.ResetDate,
+		UpdatedAt:   latest.Time(),
+	}
+
+	today := dayStart(now.In(loc))
+	first := today.AddDate(0, 0, -(days - 1))
+	byDay := make(map[string]float64)
+	burnSince := now.Add(-burnWindow)
+	burnUsed := 0.0
+	var burnStart time.Time
+
+	var prev *Snapshot
+	for i := range snapshots {
+		current := &snapshots[i]
+		at := current.Time()
+		cur, ok := current.Quotas[name]
+		if !ok || cur.Unlimited {
+			prev = nil
+			continue
+		}
+		if burnStart.IsZero() && !at.Before(burnSince) {
+			burnStart = at
+		}
+		if prev != nil {
+			before := prev.Quotas[name]
+			used := before.Remaining - cur.Remaining
+			if used > 0 && prev.ResetDate == current.ResetDate && before.Entitlement == cur.Entitlement {
+				byDay[at.In(loc).Format("2006-01-02")] += used
+				if !at.Before(burnSince) {
+					burnUsed += used
+					if prev.Time().Before(burnSince) {
+						burnStart = burnSince
+					}
+				}
+			}
+		}
+		prev = current
+	}
+
+	values := make([]float64, 0, days)
+	for day := first; !day.After(today); day = day.AddDate(0, 0, 1) {
+		key := day.Format("2006-01-02")
+		t.Days = append(t.Days, DayUsage{Day: key, Used: byDay[key]})
+		values = append(values, byDay[key])
+	}
+	t.Sparkline = Sparkline(values)
+
+	if span := latest.Time().Sub(burnStart); !burnStart.IsZero() && span >= time.Hour {
+		t.DailyBurn = round2(burnUsed / (span.Hours() / 24))
+	}
+
+	var exhaustion time.Time
+	switch {
+	case t.Remaining <= 0:
+		exhaustion = latest.Time()
+	case t.DailyBurn > 0:
+		exhaustion = latest.Time().Add(time.Duration(t.Remaining / t.DailyBurn * float64(24*time.Hour)))
+	default:
+		return t
+	}
+	t.ProjectedExhaustion = exhaustion.In(loc).Format("2006-01-02")
+	if reset, err := time.ParseInLocation("2006-01-02", t.ResetDate, loc); err == nil {
+		t.ExhaustsBeforeReset = exhaustion.Before(reset)
+		spare := round2(exhaustion.Sub(reset).Hours() / 24)
+		t.DaysToSpare = &spare
+	}
+	return t
+}
+
+func round2(value float64) float64 {
+	return math.Round(value*100) / 100
+}
+
+func quotaRank(name string) int {
+	switch name {
+	case "premium_interactions":
+		return 0
+	case "chat":
+		return 1
+	case "completions":
+		return 2
+	}
+	return 3
+}
+
+func dayStart(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+}
+
+var sparkBars = []rune("▁▂▃▄▅▆▇█")
+
+// Sparkline draws values as block characters scaled to the largest one.
+func Sparkline(values []float64) string {
+	peak := 0.0
+	for _, value := range values {
+		peak = max(peak, value)
+	}
+	var b strings.Builder
+	for _, value := range values {
+		index := 0
+		if peak > 0 && value > 0 {
+			index = 1 + int(value/peak*float64(len(sparkBars)-2)+0.5)
+		}
+		b.WriteRune(sparkBars[min(index, len(sparkBars)-1)])
+	}
+	return b.String()
+}
+
+// WriteTrends prints trends as a short text summary per quota.
+func WriteTrends(w io.Writer, trends []Trend) {
+	if len(trends) == 0 {
+		fmt.Fprintln(w, "No quota snapshots recorded.")
+		return
+	}
+	for i, t := range trends {
+		if i > 0 {
+			fmt.Fprintln(w)
+		}
+		fmt.Fprintf(w, "%s (as of %s)\n", t.Quota, t.UpdatedAt.Local().Format("2006-01-02 15:04"))
+		fmt.Fprintf(w, "  remaining   %s / %s\n", formatAmount(t.Remaining), formatAmount(t.Entitlement))
+		fmt.Fprintf(w, "  daily burn  %s (7-day average)\n", formatAmount(t.DailyBurn))
+		if len(t.Days) > 0 {
+			peak := 0.0
+			for _, day := range t.Days {
+				peak = max(peak, day.Used)
+			}
+			fmt.Fprintf(w, "  last %dd    %s  (peak %s/day)\n", len(t.Days), t.Sparkline, formatAmount(peak))
+		}
+		fmt.Fprintf(w, "  resets      %s\n", cmp.Or(t.ResetDate, "unknown"))
+		switch {
+		case t.ProjectedExhaustion == "":
+			fmt.Fprintln(w, "  projection  not being used")
+		case t.DaysToSpare == nil:
+			fmt.Fprintf(w, "  projection  runs out %s\n", t.ProjectedExhaustion)
+		case t.ExhaustsBeforeReset:
+			fmt.Fprintf(w, "  projection  runs out %s, %.1f days before the reset\n", t.ProjectedExhaustion, -*t.DaysToSpare)
+		default:
+			fmt.Fprintf(w, "  projection  lasts until the reset (would run out %s)\n", t.ProjectedExhaustion)
+		}
+	}
+}
+
+func formatAmount(value float64) string {
+	if value == float64(int64(value)) {
+		return fmt.Sprintf("%d", int64(value))
+	}
+	return fmt.Sprintf("%.1f", value)
+}
+
+// FilterTrends keeps the trend of quota only; an empty quota keeps all.
+func FilterTrends(trends []Trend, quota string) []Trend {
+	if quota == "" {
+		return trends
+	}
+	filtered := []Trend{}
+	for _, t := range trends {
+		if t.Quota == quota {
+			filtered = append(filtered, t)
+		}
+	}
+	return filtered
+}